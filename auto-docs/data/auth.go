@@ -0,0 +1,139 @@
+package data
+
+import (
+	"fmt"
+	"net"
+	"strings"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+// tokenUser is the username sent alongside a token when one has
+// not been configured, as most hosts ignore it entirely.
+const tokenUser = "auto-docs"
+
+// authMethod will select the authentication to use for the remote
+// based on the scheme of the URI. SSH remotes make use of the key
+// file or the SSH agent, while HTTP(S) remotes make use of a token
+// or a username and password when provided, and are anonymous
+// otherwise.
+func authMethod(g autodocs.Git) (transport.AuthMethod, error) {
+	e, err := transport.NewEndpoint(g.URI)
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse uri: %s", err)
+	}
+
+	switch e.Protocol {
+	case "ssh":
+		cb, err := hostKeyCallback(g)
+		if err != nil {
+			return nil, fmt.Errorf("unable to setup ssh: %s", err)
+		}
+
+		u := g.Username
+		if u == "" {
+			u = e.User
+		}
+
+		if g.SSHAgent {
+			auth, err := ssh.NewSSHAgentAuth(u)
+			if err != nil {
+				return nil, fmt.Errorf("unable to setup ssh agent: %s", err)
+			}
+
+			auth.HostKeyCallback = cb
+			return auth, nil
+		}
+
+		auth, err := ssh.NewPublicKeysFromFile(u, g.SSHKey, g.Password)
+		if err != nil {
+			return nil, fmt.Errorf("unable to setup ssh: %s", err)
+		}
+
+		auth.HostKeyCallback = cb
+		return auth, nil
+
+	case "http", "https":
+		if g.Token != "" {
+			u := g.Username
+			if u == "" {
+				u = tokenUser
+			}
+
+			return &http.BasicAuth{Username: u, Password: g.Token}, nil
+		}
+		if g.Username != "" || g.Password != "" {
+			return &http.BasicAuth{Username: g.Username, Password: g.Password}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// hostKeyCallback will give the verification for the host key of
+// an SSH remote. Pinned keys and the known_hosts file are each
+// trusted when given, otherwise the default known_hosts files are
+// used, unless verification has been explicitly disabled.
+func hostKeyCallback(g autodocs.Git) (gossh.HostKeyCallback, error) {
+	if g.InsecureIgnoreHostKey {
+		return gossh.InsecureIgnoreHostKey(), nil
+	}
+
+	var known gossh.HostKeyCallback
+	if g.KnownHosts != "" {
+		var err error
+		if known, err = knownhosts.New(g.KnownHosts); err != nil {
+			return nil, fmt.Errorf("unable to read known hosts: %s", err)
+		}
+	}
+	if len(g.HostKeys) == 0 {
+		if known != nil {
+			return known, nil
+		}
+
+		return ssh.NewKnownHostsCallback()
+	}
+
+	return func(host string, remote net.Addr, key gossh.PublicKey) error {
+		f := gossh.FingerprintSHA256(key)
+		for _, x := range g.HostKeys {
+			if strings.TrimSpace(x) == f {
+				return nil
+			}
+		}
+		if known != nil && known(host, remote, key) == nil {
+			return nil
+		}
+
+		return fmt.Errorf("host key %s for %s is not trusted", f, host)
+	}, nil
+}
+
+// SameRemote will compare two remote URIs, ignoring differences in
+// scheme, user and a trailing .git so that the SSH and HTTPS forms
+// of a repository are considered equal.
+func SameRemote(a, b string) bool {
+	x, err := transport.NewEndpoint(a)
+	if err != nil {
+		return false
+	}
+	y, err := transport.NewEndpoint(b)
+	if err != nil {
+		return false
+	}
+
+	return remoteKey(x) == remoteKey(y)
+}
+
+// remoteKey gives the host and path of an endpoint in a form
+// suitable for comparison.
+func remoteKey(e *transport.Endpoint) string {
+	p := strings.Trim(strings.TrimSuffix(strings.Trim(e.Path, "/"), ".git"), "/")
+
+	return strings.ToLower(e.Host + "/" + p)
+}
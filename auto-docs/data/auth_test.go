@@ -0,0 +1,285 @@
+package data
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/crypto/ssh/knownhosts"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+)
+
+type authStruct struct {
+	Git     autodocs.Git
+	ExpAuth transport.AuthMethod
+	ExpErr  bool
+	M       string
+}
+
+func TestAuthMethod(t *testing.T) {
+	assert := assert.New(t)
+	x := []authStruct{
+		{
+			Git:     autodocs.Git{URI: "https://example.com/a/b.git"},
+			ExpAuth: nil,
+			M:       "HTTPS with no credentials should be anonymous.",
+		},
+		{
+			Git:     autodocs.Git{URI: "https://example.com/a/b.git", Username: "ad", Password: "secret"},
+			ExpAuth: &http.BasicAuth{Username: "ad", Password: "secret"},
+			M:       "HTTPS with a password should use basic auth.",
+		},
+		{
+			Git:     autodocs.Git{URI: "http://example.com/a/b.git", Token: "abc123"},
+			ExpAuth: &http.BasicAuth{Username: tokenUser, Password: "abc123"},
+			M:       "HTTP with a token should use basic auth.",
+		},
+		{
+			Git:     autodocs.Git{URI: "https://example.com/a/b.git", Username: "oauth2", Token: "abc123", Password: "x"},
+			ExpAuth: &http.BasicAuth{Username: "oauth2", Password: "abc123"},
+			M:       "A token should take precedence over a password.",
+		},
+		{
+			Git:     autodocs.Git{URI: "/tmp/repo", SSHKey: "/does/not/exist"},
+			ExpAuth: nil,
+			M:       "A local path should need no auth.",
+		},
+		{
+			Git:    autodocs.Git{URI: "git@example.com:a/b.git", SSHKey: "/does/not/exist"},
+			ExpErr: true,
+			M:      "SSH with a missing key should error.",
+		},
+	}
+
+	for _, a := range x {
+		auth, err := authMethod(a.Git)
+		assert.Equal(a.ExpAuth, auth, a.M)
+		assert.Equal(a.ExpErr, err != nil, a.M)
+	}
+}
+
+func TestPrepareHTTP(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-auth")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	_, h, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+
+	srv := gitServer(t, dir, "ad", "token")
+	defer srv.Close()
+
+	x := []struct {
+		Git    autodocs.Git
+		ExpErr bool
+		M      string
+	}{
+		{
+			Git:    autodocs.Git{Username: "ad", Token: "wrong"},
+			ExpErr: true,
+			M:      "Incorrect credentials should fail to clone.",
+		},
+		{
+			Git:    autodocs.Git{Username: "ad", Token: "token"},
+			ExpErr: false,
+			M:      "Token credentials should clone.",
+		},
+		{
+			Git:    autodocs.Git{Username: "ad", Password: "token"},
+			ExpErr: false,
+			M:      "Password credentials should clone.",
+		},
+	}
+
+	for i, a := range x {
+		a.Git.Branch = "master"
+		a.Git.LocalPath = filepath.Join(dir, "local", fmt.Sprint(i))
+		a.Git.URI = srv.URL + "/remote/.git"
+
+		s := &State{Git: a.Git}
+		err := s.Prepare()
+		assert.Equal(a.ExpErr, err != nil, a.M)
+		if err == nil {
+			assert.Equal(h.String(), s.Revision(), a.M)
+		}
+	}
+
+	anon := gitServer(t, dir, "", "")
+	defer anon.Close()
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local", "anon"),
+		URI:       anon.URL + "/remote/.git",
+	}}
+	assert.Nil(s.Prepare(), "Public remotes should clone anonymously.")
+}
+
+type remoteStruct struct {
+	A   string
+	B   string
+	Exp bool
+	M   string
+}
+
+func TestSameRemote(t *testing.T) {
+	assert := assert.New(t)
+	x := []remoteStruct{
+		{
+			A:   "git@github.com:cloudcloud/auto-docs.git",
+			B:   "https://github.com/cloudcloud/auto-docs",
+			Exp: true,
+			M:   "SSH and HTTPS forms should match.",
+		},
+		{
+			A:   "ssh://git@GitHub.com/cloudcloud/auto-docs.git",
+			B:   "git://github.com/cloudcloud/auto-docs.git",
+			Exp: true,
+			M:   "Scheme and case should be ignored.",
+		},
+		{
+			A:   "git@github.com:cloudcloud/auto-docs.git",
+			B:   "git@github.com:cloudcloud/other.git",
+			Exp: false,
+			M:   "Different repositories should not match.",
+		},
+		{
+			A:   "git@github.com:cloudcloud/auto-docs.git",
+			B:   "git@gitlab.com:cloudcloud/auto-docs.git",
+			Exp: false,
+			M:   "Different hosts should not match.",
+		},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, SameRemote(a.A, a.B), a.M)
+	}
+}
+
+type hostKeyStruct struct {
+	Git    autodocs.Git
+	Key    gossh.PublicKey
+	ExpErr bool
+	M      string
+}
+
+func TestHostKeyCallback(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-auth")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	trusted, other := hostKey(t), hostKey(t)
+	known := filepath.Join(dir, "known_hosts")
+	assert.Nil(ioutil.WriteFile(known, []byte(knownhosts.Line([]string{"example.com"}, trusted)+"\n"), 0644))
+
+	x := []hostKeyStruct{
+		{
+			Git:    autodocs.Git{KnownHosts: known},
+			Key:    trusted,
+			ExpErr: false,
+			M:      "A key within known hosts should be trusted.",
+		},
+		{
+			Git:    autodocs.Git{KnownHosts: known},
+			Key:    other,
+			ExpErr: true,
+			M:      "A key missing from known hosts should not be trusted.",
+		},
+		{
+			Git:    autodocs.Git{HostKeys: []string{gossh.FingerprintSHA256(other)}},
+			Key:    other,
+			ExpErr: false,
+			M:      "A pinned key should be trusted.",
+		},
+		{
+			Git:    autodocs.Git{HostKeys: []string{gossh.FingerprintSHA256(other)}},
+			Key:    trusted,
+			ExpErr: true,
+			M:      "A key that is not pinned should not be trusted.",
+		},
+		{
+			Git:    autodocs.Git{HostKeys: []string{gossh.FingerprintSHA256(other)}, KnownHosts: known},
+			Key:    trusted,
+			ExpErr: false,
+			M:      "Known hosts should be trusted alongside pinned keys.",
+		},
+		{
+			Git:    autodocs.Git{InsecureIgnoreHostKey: true, KnownHosts: known},
+			Key:    other,
+			ExpErr: false,
+			M:      "Any key should be accepted when verification is disabled.",
+		},
+	}
+
+	addr := &net.TCPAddr{IP: net.ParseIP("192.0.2.1"), Port: 22}
+	for _, a := range x {
+		cb, err := hostKeyCallback(a.Git)
+		assert.Nil(err, a.M)
+		assert.Equal(a.ExpErr, cb("example.com:22", addr, a.Key) != nil, a.M)
+	}
+
+	_, err = hostKeyCallback(autodocs.Git{KnownHosts: filepath.Join(dir, "missing")})
+	assert.Error(err, "A missing known hosts file should error.")
+}
+
+func TestSSHAgent(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-auth")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	g := autodocs.Git{InsecureIgnoreHostKey: true, SSHAgent: true, URI: "git@example.com:a/b.git"}
+
+	os.Setenv("SSH_AUTH_SOCK", "")
+	_, err = authMethod(g)
+	assert.Error(err, "A missing agent should error.")
+
+	l, err := net.Listen("unix", filepath.Join(dir, "agent.sock"))
+	assert.Nil(err)
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go agent.ServeAgent(agent.NewKeyring(), c)
+		}
+	}()
+
+	os.Setenv("SSH_AUTH_SOCK", l.Addr().String())
+	defer os.Unsetenv("SSH_AUTH_SOCK")
+
+	auth, err := authMethod(g)
+	assert.Nil(err, "A running agent should be used.")
+	assert.IsType(&ssh.PublicKeysCallback{}, auth)
+	assert.Equal("git", auth.(*ssh.PublicKeysCallback).User, "The user should come from the URI.")
+}
+
+// hostKey will generate a public key for use as a host key.
+func hostKey(t *testing.T) gossh.PublicKey {
+	pub, _, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := gossh.NewPublicKey(pub)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return k
+}
@@ -1,109 +1,63 @@
 // Package data provides functionality to interact with sources
 // of documentation data.
 //
-// In the initial phase, these functions are quite... poor. Some
-// degree of effort needs investing to better process the steps
-// necessary to work with Git. There are several steps required
-// that each generate their own errors, care is required.
+// Each kind of backend is exposed as a Source, which is able to
+// prepare itself, poll for new content, and give the filesystem
+// that content can be read from. Loading the content into the
+// docs tree is left to the caller.
 package data
 
 import (
+	"fmt"
 	"log"
 	"time"
 
 	autodocs "github.com/cloudcloud/auto-docs"
-	"github.com/cloudcloud/auto-docs/auto-docs/docs"
-	git "gopkg.in/src-d/go-git.v4"
-	"gopkg.in/src-d/go-git.v4/plumbing"
-	"gopkg.in/src-d/go-git.v4/plumbing/transport/ssh"
+	"gopkg.in/src-d/go-billy.v4"
 )
 
-// State will keep a hold of the current details surrounding the
-// local data repository.
-type State struct {
-	// URL denotes the remote for this State.
-	Git autodocs.Git
+// Source is a provider of documentation content.
+type Source interface {
+	// Prepare will perform any setup required before content is
+	// available, such as cloning a repository.
+	Prepare() error
 
-	// Sha contains the current sha checked out.
-	Sha string
+	// Fetch will poll for new content, reporting whether the
+	// revision has changed since the previous check.
+	Fetch(t time.Time) (bool, error)
 
-	// g holds the git handler instance
-	g *git.Repository
-}
-
-// Prep will setup and begin the data acquisition for a specific
-// repository location.
-func Prep(g autodocs.Git) *State {
-	s := &State{
-		Git: g,
-	}
-	auth, err := ssh.NewPublicKeysFromFile(g.Username, g.SSHKey, g.Password)
-	if err != nil {
-		log.Fatalf("unable to setup ssh: %s\n", err)
-	}
-
-	_, err = git.PlainClone(
-		g.LocalPath,
-		false,
-		&git.CloneOptions{
-			Auth:          auth,
-			URL:           g.URI,
-			ReferenceName: plumbing.NewBranchReferenceName(g.Branch),
-			Depth:         1,
-			SingleBranch:  true,
-		},
-	)
-	if err != nil && err.Error() != "repository already exists" {
-		log.Println("unable to clone repository:", err)
-	}
-
-	s.g, err = git.PlainOpen(g.LocalPath)
-	if err != nil {
-		log.Println("unable to open repository:", err)
-	}
+	// Revision gives an identifier for the current content.
+	Revision() string
 
-	sha, err := s.g.Head()
-	if err != nil {
-		log.Println("unable to retrieve commit:", err)
-	}
-	s.Sha = sha.String()
+	// FS gives the filesystem content is read from.
+	FS() billy.Filesystem
+}
 
-	// tell data to update
-	docs.S.UpdateFromPath(g.LocalPath)
+// Versioned is implemented by any Source able to provide content
+// for further versions beyond the current revision.
+type Versioned interface {
+	// Versions gives each further version currently available.
+	Versions() []autodocs.Version
 
-	return s
+	// VersionFS gives the filesystem content for the version is
+	// read from.
+	VersionFS(v autodocs.Version) (billy.Filesystem, error)
 }
 
-// Fetch will check for a new sha and pull if one found.
-func (s *State) Fetch(t time.Time) {
-	// pull from the upstream
-	w, err := s.g.Worktree()
-	if err != nil {
-		log.Println("unable to get worktree:", err)
-	}
-	auth, err := ssh.NewPublicKeysFromFile(s.Git.Username, s.Git.SSHKey, s.Git.Password)
-	if err != nil {
-		log.Fatalf("unable to setup ssh: %s\n", err)
-	}
+// New will create the Source selected within the provided
+// configuration.
+func New(c autodocs.Source) (Source, error) {
+	switch c.Type {
+	case "", "git":
+		if c.Git.InsecureIgnoreHostKey {
+			log.Println("warning: ssh host key verification is disabled for source:", c.Name)
+		}
 
-	err = w.Pull(
-		&git.PullOptions{
-			Auth:          auth,
-			Depth:         1,
-			ReferenceName: plumbing.NewBranchReferenceName(s.Git.Branch),
-			SingleBranch:  true,
-		},
-	)
-	if err != nil && err.Error() != "already up-to-date" {
-		log.Println("couldn't fetch:", err)
-	}
-
-	// compare the sha against the previous known hash
-	if sha, _ := s.g.Head(); sha.String() != s.Sha {
-		// tell data to re-process
-		log.Println("updating local repo at", t)
+		return &State{Git: c.Git}, nil
 
-		s.Sha = sha.String()
-		docs.S.UpdateFromPath(s.Git.LocalPath)
+	case "local":
+		return &Local{Local: c.Local}, nil
 	}
+
+	return nil, fmt.Errorf("unknown source type: %s", c.Type)
 }
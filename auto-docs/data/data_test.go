@@ -1,27 +1,527 @@
 package data
 
 import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cgi"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
+	autodocs "github.com/cloudcloud/auto-docs"
 	"github.com/stretchr/testify/assert"
-)
-
-var (
-	repoPath = ""
+	"gopkg.in/src-d/go-billy.v4"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
 )
 
 func TestPrepBase(t *testing.T) {
 	assert := assert.New(t)
-	_ = assert
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	_, h, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare(), "Cloning a local remote should succeed.")
+	assert.Equal(h.String(), s.Revision(), "Revision should be the remote head.")
+	assert.FileExists(filepath.Join(s.Git.LocalPath, "readme.md"), "Content should be checked out.")
+
+	assert.Nil(s.Prepare(), "Preparing an existing clone should succeed.")
+
+	assert.FileExists(s.Git.LocalPath + ".lock")
+	assert.Nil(s.Close())
+	_, err = os.Stat(s.Git.LocalPath + ".lock")
+	assert.True(os.IsNotExist(err), "Closing should release the lock.")
 }
 
 func TestFetchBase(t *testing.T) {
 	assert := assert.New(t)
-	_ = assert
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	_, err = (&State{}).Fetch(time.Now())
+	assert.Error(err, "Fetching before preparing should error.")
+
+	r, _, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+
+	changed, err := s.Fetch(time.Now())
+	assert.Nil(err)
+	assert.False(changed, "An unchanged remote should not change.")
+
+	h, err := commitFiles(r, map[string]string{"other.md": "# other"})
+	assert.Nil(err)
+
+	changed, err = s.Fetch(time.Now())
+	assert.Nil(err)
+	assert.True(changed, "A new commit should be a change.")
+	assert.Equal(h.String(), s.Revision(), "Revision should follow the remote.")
+}
+
+func TestRevisionDuringFetch(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, _, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+
+	h, err := commitFiles(r, map[string]string{"other.md": "# other"})
+	assert.Nil(err)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			s.Revision()
+		}
+	}()
+
+	_, err = s.Fetch(time.Now())
+	assert.Nil(err)
+	<-done
+	assert.Equal(h.String(), s.Revision(), "Revision should be safe to read while fetching.")
+}
+
+func TestReadDuringFetch(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, _, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		History:   true,
+		LocalPath: filepath.Join(dir, "local"),
+		Timeout:   5000,
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+	rev := s.Revision()
+
+	h, err := commitFiles(r, map[string]string{"other.md": "# other"})
+	assert.Nil(err)
+
+	// hold up the fetch as if the remote were slow
+	s.busy <- struct{}{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Fetch(time.Now())
+	}()
+	time.Sleep(50 * time.Millisecond)
+
+	files, err := s.Files(rev)
+	assert.Nil(err)
+	assert.Equal([]string{"readme.md"}, files)
+	select {
+	case <-done:
+		assert.Fail("Reading should not wait for the fetch.")
+	default:
+	}
+
+	<-s.busy
+	<-done
+	assert.Equal(h.String(), s.Revision(), "The fetch should go on once the remote is free.")
+}
+
+func TestFetchForcePush(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, base, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+	_, err = commitFiles(r, map[string]string{"old.md": "# old"})
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+
+	// rewrite the remote history
+	w, err := r.Worktree()
+	assert.Nil(err)
+	assert.Nil(w.Reset(&git.ResetOptions{Commit: base, Mode: git.HardReset}))
+	h, err := commitFiles(r, map[string]string{"new.md": "# new"})
+	assert.Nil(err)
+
+	// dirty the local checkout
+	assert.Nil(ioutil.WriteFile(filepath.Join(s.Git.LocalPath, "readme.md"), []byte("# local"), 0644))
+	assert.Nil(ioutil.WriteFile(filepath.Join(s.Git.LocalPath, "stray.md"), []byte("# stray"), 0644))
+
+	changed, err := s.Fetch(time.Now())
+	assert.Nil(err, "A force-push should be followed.")
+	assert.True(changed)
+	assert.Equal(h.String(), s.Revision(), "Revision should be the rewritten head.")
+	assert.FileExists(filepath.Join(s.Git.LocalPath, "new.md"))
+
+	b, err := ioutil.ReadFile(filepath.Join(s.Git.LocalPath, "readme.md"))
+	assert.Nil(err)
+	assert.Equal("# readme", string(b), "Local changes should be discarded.")
+
+	for _, f := range []string{"old.md", "stray.md"} {
+		_, err = os.Stat(filepath.Join(s.Git.LocalPath, f))
+		assert.True(os.IsNotExist(err), "Files not on the remote should be removed.")
+	}
+}
+
+func TestFetchRecover(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, _, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+
+	h, err := commitFiles(r, map[string]string{"other.md": "# other"})
+	assert.Nil(err)
+	assert.Nil(ioutil.WriteFile(filepath.Join(s.Git.LocalPath, ".git", "HEAD"), []byte("garbage"), 0644))
+
+	changed, err := s.Fetch(time.Now())
+	assert.Nil(err, "A corrupted checkout should be cloned again.")
+	assert.True(changed)
+	assert.Equal(h.String(), s.Revision())
+
+	o := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "elsewhere"),
+	}}
+	assert.Error(o.Prepare(), "A checkout of another remote should not be reused.")
+}
+
+func TestFetchMemory(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, v1, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+	_, err = r.CreateTag("v1", v1, nil)
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		Memory:    true,
+		Tags:      []string{"v*"},
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+	assert.Equal(v1.String(), s.Revision(), "The branch should be checked out.")
+
+	b, err := readAll(s.FS(), "readme.md")
+	assert.Nil(err)
+	assert.Equal("# readme", b, "Content should be checked out in memory.")
+
+	h, err := commitFiles(r, map[string]string{"readme.md": "# changed"})
+	assert.Nil(err)
+	changed, err := s.Fetch(time.Now())
+	assert.Nil(err)
+	assert.True(changed, "A new commit should be a change.")
+	assert.Equal(h.String(), s.Revision(), "Revision should follow the remote.")
+
+	b, err = readAll(s.FS(), "readme.md")
+	assert.Nil(err)
+	assert.Equal("# changed", b, "The checkout should follow the remote.")
+
+	fs, err := s.VersionFS(s.Versions()[0])
+	assert.Nil(err)
+	b, err = readAll(fs, "readme.md")
+	assert.Nil(err)
+	assert.Equal("# readme", b, "A version should have the content at its commit.")
+
+	for _, p := range []string{"local", "local.lock", "local.versions"} {
+		_, err = os.Stat(filepath.Join(dir, p))
+		assert.True(os.IsNotExist(err), "Nothing should be written to disk.")
+	}
+}
+
+func TestPrepTimeout(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		Timeout:   100,
+		URI:       srv.URL + "/remote.git",
+	}}
+
+	start := time.Now()
+	assert.Error(s.Prepare(), "A hung remote should abort.")
+	assert.True(time.Since(start) < 5*time.Second, "Aborting should respect the timeout.")
+}
+
+func TestPrepAbandoned(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	resetTransport()
+	defer resetTransport()
+
+	done := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-done
+	}))
+	defer srv.Close()
+	defer close(done)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		Timeout:   100,
+		URI:       srv.URL + "/remote.git",
+	}}
+	assert.Error(s.Prepare(), "A hung remote should abort.")
+
+	s.Git.CloneTimeout = 5000
+	err = s.Prepare()
+	assert.Error(err)
+	assert.NotContains(err.Error(), "still running", "An abandoned operation should not hold up the next for good.")
+}
+
+func TestUseTransport(t *testing.T) {
+	assert := assert.New(t)
+	resetTransport()
+	defer resetTransport()
+
+	useTransport(autodocs.Git{Timeout: 100, URI: "git@example.com:docs.git"})
+	assert.False(httpClient.installed, "Only HTTP(S) remotes should install the client.")
+
+	for _, x := range []struct {
+		Timeout int
+		Exp     time.Duration
+	}{
+		{200, 200 * time.Millisecond},
+		{100, 200 * time.Millisecond},
+		{300, 300 * time.Millisecond},
+		{0, 0},
+		{400, 0},
+	} {
+		useTransport(autodocs.Git{Timeout: x.Timeout, URI: "https://example.com/docs.git"})
+		assert.Equal(x.Exp, httpClient.timeout, "The longest wait of any source should be kept.")
+	}
+}
+
+// resetTransport puts back the client go-git uses by default for
+// HTTP(S) remotes.
+func resetTransport() {
+	httpClient.installed, httpClient.timeout = false, 0
+	client.InstallProtocol("http", githttp.DefaultClient)
+	client.InstallProtocol("https", githttp.DefaultClient)
+}
+
+func TestFetchRetry(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Error(s.Prepare(), "A missing remote should fail to prepare.")
+
+	_, h, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+
+	changed, err := s.Fetch(time.Now())
+	assert.Nil(err, "Fetching should retry preparing.")
+	assert.True(changed, "A successful retry should be a change.")
+	assert.Equal(h.String(), s.Revision())
+}
+
+type newStruct struct {
+	Config autodocs.Source
+	ExpSrc Source
+	ExpErr error
+	M      string
+}
+
+func TestNew(t *testing.T) {
+	assert := assert.New(t)
+	x := []newStruct{
+		{
+			Config: autodocs.Source{Git: autodocs.Git{URI: "git@example.com:a/b.git"}},
+			ExpSrc: &State{Git: autodocs.Git{URI: "git@example.com:a/b.git"}},
+			ExpErr: nil,
+			M:      "No source type should default to git.",
+		},
+		{
+			Config: autodocs.Source{Type: "local", Local: autodocs.Local{Path: "/docs"}},
+			ExpSrc: &Local{Local: autodocs.Local{Path: "/docs"}},
+			ExpErr: nil,
+			M:      "Local source type should give a local source.",
+		},
+		{
+			Config: autodocs.Source{Type: "svn"},
+			ExpSrc: nil,
+			ExpErr: fmt.Errorf("unknown source type: svn"),
+			M:      "Unknown source type should error.",
+		},
+	}
+
+	for _, a := range x {
+		src, err := New(a.Config)
+		assert.Equal(a.ExpSrc, src, a.M)
+		assert.Equal(a.ExpErr, err, a.M)
+	}
+}
+
+// initRepo will create a repository at the path with a single
+// commit containing the provided files.
+func initRepo(p string, files map[string]string) (*git.Repository, plumbing.Hash, error) {
+	r, err := git.PlainInit(p, false)
+	if err != nil {
+		return nil, plumbing.ZeroHash, err
+	}
+
+	h, err := commitFiles(r, files)
+	return r, h, err
+}
+
+// commitFiles will write the provided files into the worktree of
+// the repository and commit them.
+func commitFiles(r *git.Repository, files map[string]string) (plumbing.Hash, error) {
+	return commitFilesAt(r, files, time.Now())
+}
+
+// commitFilesAt will commit the provided files as if at the time.
+func commitFilesAt(r *git.Repository, files map[string]string, when time.Time) (plumbing.Hash, error) {
+	return commitFilesWith(r, files, &git.CommitOptions{
+		Author:    &object.Signature{Name: "auto-docs", Email: "auto-docs@example.com", When: when},
+		Committer: &object.Signature{Name: "auto-docs", Email: "auto-docs@example.com", When: when},
+	})
+}
+
+// commitFilesWith will commit the provided files with the options.
+func commitFilesWith(r *git.Repository, files map[string]string, o *git.CommitOptions) (plumbing.Hash, error) {
+	w, err := r.Worktree()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	for name, content := range files {
+		p := filepath.Join(w.Filesystem.Root(), name)
+		if err := os.MkdirAll(filepath.Dir(p), 0755); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if err := ioutil.WriteFile(p, []byte(content), 0644); err != nil {
+			return plumbing.ZeroHash, err
+		}
+		if _, err := w.Add(name); err != nil {
+			return plumbing.ZeroHash, err
+		}
+	}
+
+	return w.Commit("update docs", o)
+}
+
+// defaultSignature gives a signature for use in tests.
+func defaultSignature() *object.Signature {
+	return &object.Signature{Name: "auto-docs", Email: "auto-docs@example.com", When: time.Now()}
+}
+
+// gitServer will serve the repositories within root over HTTP by
+// way of git http-backend, requiring basic auth when a user has
+// been provided.
+func gitServer(t *testing.T, root, user, pass string) *httptest.Server {
+	out, err := exec.Command("git", "--exec-path").Output()
+	if err != nil {
+		t.Skip("git is not available:", err)
+	}
+
+	backend := filepath.Join(strings.TrimSpace(string(out)), "git-http-backend")
+	if _, err := os.Stat(backend); err != nil {
+		t.Skip("git http-backend is not available:", err)
+	}
+
+	h := &cgi.Handler{
+		Path: backend,
+		Env:  []string{"GIT_PROJECT_ROOT=" + root, "GIT_HTTP_EXPORT_ALL=1"},
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if u, p, ok := r.BasicAuth(); user != "" && (!ok || u != user || p != pass) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="git"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		h.ServeHTTP(w, r)
+	}))
 }
 
-func initEmptyRepo() error {
-	//
+// readAll gives the content of a file within the filesystem.
+func readAll(fs billy.Filesystem, p string) (string, error) {
+	f, err := fs.Open(p)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
 
-	return nil
+	b, err := ioutil.ReadAll(f)
+	return string(b), err
 }
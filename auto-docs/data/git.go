@@ -0,0 +1,493 @@
+package data
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/storage/memory"
+)
+
+// State will keep a hold of the current details surrounding the
+// local data repository.
+type State struct {
+	// URL denotes the remote for this State.
+	Git autodocs.Git
+
+	// Sha contains the current sha checked out.
+	Sha string
+
+	// mu guards the repository and Sha while they are changed, as
+	// they may be read at the same time. Only Prepare and Fetch
+	// change them, which are not to be called at the same time.
+	mu sync.RWMutex
+
+	// busy holds a token while an operation against the remote is
+	// running, including one abandoned after its timeout.
+	busy chan struct{}
+
+	// g holds the git handler instance
+	g *git.Repository
+
+	// l is held over the local path once prepared.
+	l *lock
+
+	// trees keeps the content of each version, by commit sha.
+	trees map[string]billy.Filesystem
+
+	// unverified is the remote head refused for not being signed
+	// by a trusted key, if any.
+	unverified string
+
+	// versions holds each further branch and tag being tracked.
+	versions []autodocs.Version
+}
+
+// Prepare will clone the repository into the local path, or
+// open the existing copy if one has already been cloned. Should
+// the existing copy be unusable, it is removed and cloned again.
+// With Memory set, the repository is only ever held in memory.
+func (s *State) Prepare() error {
+	useTransport(s.Git)
+	if s.Git.Memory {
+		return s.clone()
+	}
+	if s.Git.LocalPath == "" {
+		return fmt.Errorf("no local path to check out to")
+	}
+
+	if err := s.hold(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	err := s.open()
+	s.mu.Unlock()
+	if err == nil {
+		return nil
+	}
+
+	return s.clone()
+}
+
+// hold will lock the local path, unless it is already held.
+func (s *State) hold() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.l != nil {
+		return nil
+	}
+
+	l := &lock{
+		path:  s.Git.LocalPath + ".lock",
+		stale: 3 * time.Duration(s.Git.Period) * time.Second,
+	}
+	if err := l.acquire(); err != nil {
+		return err
+	}
+
+	s.l = l
+	return nil
+}
+
+// Close will release the lock over the local path, for when the
+// process is shutting down.
+func (s *State) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.l == nil {
+		return nil
+	}
+
+	err := s.l.release()
+	s.l = nil
+	return err
+}
+
+// Fetch will check for a new sha on the remote branch, and move
+// the local checkout to it if one is found. When the repository
+// could not previously be prepared, this is retried. The remote is
+// reached without holding mu, so that the repository may still be
+// read in the meantime.
+func (s *State) Fetch(t time.Time) (bool, error) {
+	if s.g == nil {
+		if err := s.Prepare(); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	s.mu.Lock()
+	if s.l != nil {
+		s.l.refresh()
+	}
+	s.mu.Unlock()
+	s.pruneRoots()
+	prev := s.Sha
+
+	auth, err := authMethod(s.Git)
+	if err != nil {
+		return false, err
+	}
+
+	names, err := s.listVersions(auth)
+	if err != nil {
+		return false, err
+	}
+
+	err = s.fetch(auth, names)
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		s.mu.Lock()
+		usable := s.open() == nil
+		s.mu.Unlock()
+		if err != plumbing.ErrObjectNotFound && usable {
+			return false, fmt.Errorf("couldn't fetch: %s", err)
+		}
+
+		// the failure came from a broken or shallow local copy
+		// that no longer shares history with the remote
+		if err := s.clone(); err != nil {
+			return false, fmt.Errorf("unable to recover repository: %s", err)
+		}
+	}
+
+	s.mu.Lock()
+	err = s.reset()
+	s.mu.Unlock()
+	if err != nil {
+		if errors.Is(err, errUntrusted) {
+			return false, err
+		}
+
+		// the local copy can't be trusted, so start again
+		if err := s.clone(); err != nil {
+			return false, fmt.Errorf("unable to recover repository: %s", err)
+		}
+	}
+	if err := s.updateSubmodules(); err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.updateVersions(names); err != nil {
+		return false, err
+	}
+
+	// compare the sha against the previous known hash
+	sha, err := s.g.Head()
+	if err != nil {
+		return false, fmt.Errorf("unable to retrieve commit: %s", err)
+	}
+	if sha.Hash().String() == prev {
+		return false, nil
+	}
+
+	s.Sha = sha.Hash().String()
+	return true, nil
+}
+
+// fetch will fetch the branch and each listed version from the
+// remote. A copy on disk is fetched through a handle of its own,
+// which replaces the one being read once done. A copy in memory
+// can't be read while it is written to, so mu is held throughout.
+func (s *State) fetch(auth transport.AuthMethod, names map[plumbing.ReferenceName]bool) error {
+	g := s.g
+	if s.Git.Memory {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+	} else {
+		var err error
+		if g, err = git.PlainOpen(s.Git.LocalPath); err != nil {
+			return fmt.Errorf("unable to open repository: %s", err)
+		}
+	}
+
+	err := s.remote(s.Git.Timeout, func(ctx context.Context) error {
+		return g.FetchContext(
+			ctx,
+			&git.FetchOptions{
+				Auth:     auth,
+				Depth:    s.depth(),
+				Force:    true,
+				RefSpecs: append([]config.RefSpec{s.refSpec()}, s.versionSpecs(names)...),
+				Tags:     git.NoTags,
+			},
+		)
+	})
+	if err != nil && err != git.NoErrAlreadyUpToDate {
+		return err
+	}
+
+	if !s.Git.Memory {
+		s.mu.Lock()
+		s.g = g
+		s.mu.Unlock()
+	}
+
+	return err
+}
+
+// open will load an existing copy of the repository from the
+// local path, or memory, checking that it is for the configured
+// remote. This is called with mu held.
+func (s *State) open() error {
+	g := s.g
+	if !s.Git.Memory {
+		var err error
+		if g, err = git.PlainOpen(s.Git.LocalPath); err != nil {
+			return fmt.Errorf("unable to open repository: %s", err)
+		}
+	}
+	if g == nil {
+		return fmt.Errorf("repository has not been cloned")
+	}
+
+	r, err := g.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return fmt.Errorf("unable to find remote: %s", err)
+	}
+	if u := r.Config().URLs; len(u) == 0 || u[0] != s.Git.URI {
+		return fmt.Errorf("repository is for another remote: %v", u)
+	}
+
+	if s.Git.History {
+		if c, err := g.Storer.Shallow(); err != nil || len(c) > 0 {
+			return fmt.Errorf("repository is missing history")
+		}
+	}
+
+	sha, err := g.Head()
+	if err != nil {
+		return fmt.Errorf("unable to retrieve commit: %s", err)
+	}
+	if s.Git.Keyring != "" {
+		keys, err := s.keyring()
+		if err != nil {
+			return err
+		}
+		if c, err := g.CommitObject(sha.Hash()); err != nil || !trusted(c, keys) {
+			return fmt.Errorf("checkout is not signed by a trusted key")
+		}
+	}
+
+	s.g = g
+	s.Sha = sha.Hash().String()
+	return s.loadVersions()
+}
+
+// clone will remove anything within the local path and clone the
+// repository fresh, or clone into memory with Memory set. Nothing is
+// read from the repository while it is cloned.
+func (s *State) clone() error {
+	auth, err := authMethod(s.Git)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.g = nil
+	s.mu.Unlock()
+
+	o := &git.CloneOptions{
+		Auth:          auth,
+		URL:           s.Git.URI,
+		ReferenceName: plumbing.NewBranchReferenceName(s.Git.Branch),
+		Depth:         s.depth(),
+		SingleBranch:  !s.versioned(),
+		NoCheckout:    s.Git.Keyring != "",
+	}
+
+	var g *git.Repository
+	err = s.remote(s.cloneTimeout(), func(ctx context.Context) error {
+		if s.Git.Memory {
+			r, err := git.CloneContext(ctx, memory.NewStorage(), memfs.New(), o)
+			g = r
+			return err
+		}
+
+		if err := os.RemoveAll(s.Git.LocalPath); err != nil {
+			return err
+		}
+
+		r, err := git.PlainCloneContext(ctx, s.Git.LocalPath, false, o)
+		g = r
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("unable to clone repository: %s", err)
+	}
+
+	if err := s.use(g); err != nil {
+		return err
+	}
+
+	return s.updateSubmodules()
+}
+
+// use will check out a freshly cloned repository and load it.
+func (s *State) use(g *git.Repository) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// nothing is checked out until a signed commit has been found
+	s.g = g
+	if s.Git.Keyring != "" {
+		if err := s.reset(); err != nil {
+			s.Sha = ""
+			return err
+		}
+	}
+
+	return s.open()
+}
+
+// reset will hard reset the checkout to the fetched remote branch,
+// discarding any local changes or untracked files. This copes with
+// the remote having been force-pushed or rebased. With a keyring
+// configured, only a commit signed by a trusted key is checked out.
+// This is called with mu held.
+func (s *State) reset() error {
+	ref, err := s.g.Reference(s.remoteRef(), true)
+	if err != nil {
+		return fmt.Errorf("unable to find remote branch: %s", err)
+	}
+
+	h, err := s.target(ref.Hash())
+	if err != nil {
+		return err
+	}
+
+	w, err := s.g.Worktree()
+	if err != nil {
+		return fmt.Errorf("unable to get worktree: %s", err)
+	}
+
+	err = w.Reset(&git.ResetOptions{Commit: h, Mode: git.HardReset})
+	if err != nil {
+		return fmt.Errorf("unable to reset worktree: %s", err)
+	}
+
+	err = w.Clean(&git.CleanOptions{Dir: true})
+	if err != nil {
+		return fmt.Errorf("unable to clean worktree: %s", err)
+	}
+
+	return nil
+}
+
+// depth gives the number of commits of history to fetch, where
+// zero fetches all history.
+func (s *State) depth() int {
+	if s.Git.History {
+		return 0
+	}
+
+	return 1
+}
+
+// remoteRef gives the remote tracking reference for the branch.
+func (s *State) remoteRef() plumbing.ReferenceName {
+	return plumbing.NewRemoteReferenceName(git.DefaultRemoteName, s.Git.Branch)
+}
+
+// refSpec gives the refspec to force fetch the branch into the
+// remote tracking reference.
+func (s *State) refSpec() config.RefSpec {
+	return config.RefSpec(fmt.Sprintf(
+		"+%s:%s",
+		plumbing.NewBranchReferenceName(s.Git.Branch),
+		s.remoteRef(),
+	))
+}
+
+// remote will run an operation that reaches out to the remote,
+// bound by the timeout in ms. Not every step of go-git honours the
+// context, so the operation is abandoned once the deadline has
+// passed. Another is only started once it has finished, waiting for
+// it within the same timeout.
+func (s *State) remote(timeout int, op func(context.Context) error) error {
+	ctx, cancel := context.Background(), context.CancelFunc(func() {})
+	if timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(timeout)*time.Millisecond)
+	}
+
+	if s.busy == nil {
+		s.busy = make(chan struct{}, 1)
+	}
+	select {
+	case s.busy <- struct{}{}:
+	case <-ctx.Done():
+		cancel()
+		return fmt.Errorf("previous operation against the remote still running")
+	}
+
+	done := make(chan error, 1)
+	go func(busy chan struct{}) {
+		defer func() { <-busy }()
+		defer cancel()
+
+		done <- op(ctx)
+	}(s.busy)
+
+	select {
+	case err := <-done:
+		return err
+
+	case <-ctx.Done():
+		return fmt.Errorf("timed out after %dms", timeout)
+	}
+}
+
+// cloneTimeout gives the timeout in ms for cloning the repository
+// or its submodules, falling back to the timeout for fetches.
+func (s *State) cloneTimeout() int {
+	if s.Git.CloneTimeout > 0 {
+		return s.Git.CloneTimeout
+	}
+
+	return s.Git.Timeout
+}
+
+// Revision gives the sha currently checked out.
+func (s *State) Revision() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.Sha
+}
+
+// FS gives the checked out worktree.
+func (s *State) FS() billy.Filesystem {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.Git.Memory {
+		return osfs.New(s.Git.LocalPath)
+	}
+	if s.g == nil {
+		return memfs.New()
+	}
+
+	w, err := s.g.Worktree()
+	if err != nil {
+		return memfs.New()
+	}
+
+	return w.Filesystem
+}
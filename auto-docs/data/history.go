@@ -0,0 +1,521 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+)
+
+// Historic is implemented by any Source able to provide content as
+// it was at a past revision.
+type Historic interface {
+	// Resolve gives the revision for a sha, branch, tag or date.
+	Resolve(ref string) (string, error)
+
+	// Files lists the path of every file at the revision.
+	Files(rev string) ([]string, error)
+
+	// ReadFile gives the content of a file at the revision.
+	ReadFile(rev, p string) ([]byte, error)
+
+	// Log gives each commit that changed a file, newest first,
+	// starting from the revision and following renames.
+	Log(rev, p string) ([]autodocs.Commit, error)
+
+	// LastModified gives the latest commit to change each of the
+	// files at the revision, or every file when files is nil.
+	LastModified(rev string, files []string) (map[string]autodocs.Commit, error)
+
+	// Changes gives each file added, removed, modified or renamed
+	// between two revisions.
+	Changes(from, to string) ([]autodocs.Change, error)
+}
+
+// ErrNoHistory is given when history has not been fetched.
+var ErrNoHistory = errors.New("history is not enabled")
+
+// renameSimilarity is the share of lines that must be unchanged
+// for a file removed in the same commit to be considered renamed.
+const renameSimilarity = 0.5
+
+// dateFormats lists the layouts accepted when resolving a date.
+var dateFormats = []string{time.RFC3339, "2006-01-02T15:04", "2006-01-02"}
+
+// shortSha matches an abbreviated commit sha.
+var shortSha = regexp.MustCompile(`^[0-9a-f]{4,39}$`)
+
+// Resolve will find the commit for a sha, an abbreviated sha, a
+// branch or tag name, or a date. For a date, this is the latest
+// commit on the branch at that time.
+func (s *State) Resolve(ref string) (string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.g == nil {
+		return "", fmt.Errorf("repository has not been prepared")
+	}
+
+	for _, f := range dateFormats {
+		if t, err := time.Parse(f, ref); err == nil {
+			return s.commitAt(t)
+		}
+	}
+
+	if h, err := s.g.ResolveRevision(plumbing.Revision(ref)); err == nil {
+		return h.String(), nil
+	}
+
+	if shortSha.MatchString(strings.ToLower(ref)) {
+		return s.commitPrefix(strings.ToLower(ref))
+	}
+
+	return "", fmt.Errorf("unknown revision: %s", ref)
+}
+
+// Files lists the path of every file at the commit.
+func (s *State) Files(rev string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.tree(rev)
+	if err != nil {
+		return nil, err
+	}
+
+	r := []string{}
+	err = t.Files().ForEach(func(f *object.File) error {
+		r = append(r, f.Name)
+		return nil
+	})
+
+	return r, err
+}
+
+// ReadFile gives the content of a file at the commit, read from the
+// object store rather than the worktree.
+func (s *State) ReadFile(rev, p string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	t, err := s.tree(rev)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := t.File(p)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find %s: %s", p, err)
+	}
+
+	r, err := f.Reader()
+	if err != nil {
+		return nil, fmt.Errorf("unable to read %s: %s", p, err)
+	}
+	defer r.Close()
+
+	return ioutil.ReadAll(r)
+}
+
+// Log gives each commit that changed a file, newest first. The
+// first parent of each commit is followed, and the file is tracked
+// across renames where most of the content is unchanged.
+func (s *State) Log(rev, p string) ([]autodocs.Commit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.Git.History {
+		return nil, ErrNoHistory
+	}
+	if s.g == nil {
+		return nil, fmt.Errorf("repository has not been prepared")
+	}
+
+	c, err := s.g.CommitObject(plumbing.NewHash(rev))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find commit %s: %s", rev, err)
+	}
+
+	r := []autodocs.Commit{}
+	for cur := p; c != nil; {
+		h, ok := blobOf(c, cur)
+		if !ok {
+			break
+		}
+
+		parent, _ := c.Parent(0)
+		if parent != nil {
+			if ph, ok := blobOf(parent, cur); ok && ph == h {
+				c = parent
+				continue
+			}
+		}
+		r = append(r, toCommit(c, cur))
+
+		if parent == nil {
+			break
+		}
+		if _, ok := blobOf(parent, cur); !ok {
+			// added here, unless it was moved from elsewhere
+			prev, ok := renamedFrom(parent, c, cur)
+			if !ok {
+				break
+			}
+			cur = prev
+		}
+
+		c = parent
+	}
+
+	return r, nil
+}
+
+// LastModified gives the latest commit to change each of the files
+// at the commit, or every file when files is nil, walking the first
+// parent of each commit only as far as needed to find them all.
+func (s *State) LastModified(rev string, files []string) (map[string]autodocs.Commit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.Git.History {
+		return nil, ErrNoHistory
+	}
+	if s.g == nil {
+		return nil, fmt.Errorf("repository has not been prepared")
+	}
+
+	c, err := s.g.CommitObject(plumbing.NewHash(rev))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find commit %s: %s", rev, err)
+	}
+	t, err := c.Tree()
+	if err != nil {
+		return nil, fmt.Errorf("unable to find tree for %s: %s", rev, err)
+	}
+
+	want := map[string]bool{}
+	for _, x := range files {
+		want[x] = true
+	}
+
+	pending := map[string]bool{}
+	t.Files().ForEach(func(f *object.File) error {
+		if files == nil || want[f.Name] {
+			pending[f.Name] = true
+		}
+		return nil
+	})
+
+	r := map[string]autodocs.Commit{}
+	for c != nil && len(pending) > 0 {
+		parent, _ := c.Parent(0)
+		if parent == nil {
+			for n := range pending {
+				r[n] = toCommit(c, n)
+			}
+			break
+		}
+
+		ct, err := c.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find tree for %s: %s", c.Hash, err)
+		}
+		pt, err := parent.Tree()
+		if err != nil {
+			return nil, fmt.Errorf("unable to find tree for %s: %s", parent.Hash, err)
+		}
+		changes, err := object.DiffTree(pt, ct)
+		if err != nil {
+			return nil, fmt.Errorf("unable to compare %s: %s", c.Hash, err)
+		}
+
+		for _, x := range changes {
+			if pending[x.To.Name] {
+				r[x.To.Name] = toCommit(c, x.To.Name)
+				delete(pending, x.To.Name)
+			}
+		}
+
+		c = parent
+	}
+
+	return r, nil
+}
+
+// Changes gives each file added, removed, modified or renamed
+// between two commits. A removed and an added file are paired as a
+// rename where most of the content is unchanged.
+func (s *State) Changes(from, to string) ([]autodocs.Change, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	a, err := s.tree(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.tree(to)
+	if err != nil {
+		return nil, err
+	}
+
+	changes, err := object.DiffTree(a, b)
+	if err != nil {
+		return nil, fmt.Errorf("unable to compare %s and %s: %s", from, to, err)
+	}
+
+	r := []autodocs.Change{}
+	added, removed := []string{}, []string{}
+	for _, x := range changes {
+		switch {
+		case x.From.Name == "":
+			added = append(added, x.To.Name)
+		case x.To.Name == "":
+			removed = append(removed, x.From.Name)
+		default:
+			r = append(r, autodocs.Change{Kind: autodocs.ChangeModified, From: x.From.Name, To: x.To.Name})
+		}
+	}
+	r = append(r, renames(a, b, removed, added)...)
+
+	name := func(c autodocs.Change) string {
+		if c.To == "" {
+			return c.From
+		}
+		return c.To
+	}
+	sort.Slice(r, func(i, j int) bool { return name(r[i]) < name(r[j]) })
+
+	return r, nil
+}
+
+// tree gives the tree for a commit, with mu held.
+func (s *State) tree(rev string) (*object.Tree, error) {
+	if s.g == nil {
+		return nil, fmt.Errorf("repository has not been prepared")
+	}
+
+	c, err := s.g.CommitObject(plumbing.NewHash(rev))
+	if err != nil {
+		return nil, fmt.Errorf("unable to find commit %s: %s", rev, err)
+	}
+
+	return c.Tree()
+}
+
+// commitAt gives the latest commit on the branch made at or before
+// the time.
+func (s *State) commitAt(t time.Time) (string, error) {
+	h, err := s.g.Head()
+	if err != nil {
+		return "", fmt.Errorf("unable to retrieve commit: %s", err)
+	}
+
+	i, err := s.g.Log(&git.LogOptions{From: h.Hash(), Order: git.LogOrderCommitterTime})
+	if err != nil {
+		return "", fmt.Errorf("unable to read history: %s", err)
+	}
+
+	found := ""
+	err = i.ForEach(func(c *object.Commit) error {
+		if !c.Committer.When.After(t) {
+			found = c.Hash.String()
+			return storer.ErrStop
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to read history: %s", err)
+	}
+	if found == "" {
+		return "", fmt.Errorf("no commit at or before %s", t.Format(time.RFC3339))
+	}
+
+	return found, nil
+}
+
+// commitPrefix gives the only commit whose sha starts with the
+// abbreviation.
+func (s *State) commitPrefix(p string) (string, error) {
+	i, err := s.g.CommitObjects()
+	if err != nil {
+		return "", fmt.Errorf("unable to read history: %s", err)
+	}
+
+	found := []string{}
+	err = i.ForEach(func(c *object.Commit) error {
+		if strings.HasPrefix(c.Hash.String(), p) {
+			found = append(found, c.Hash.String())
+		}
+
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to read history: %s", err)
+	}
+
+	switch len(found) {
+	case 0:
+		return "", fmt.Errorf("unknown revision: %s", p)
+	case 1:
+		return found[0], nil
+	}
+
+	return "", fmt.Errorf("ambiguous revision: %s", p)
+}
+
+// blobOf gives the hash of the file at the path within the commit.
+func blobOf(c *object.Commit, p string) (plumbing.Hash, bool) {
+	t, err := c.Tree()
+	if err != nil {
+		return plumbing.ZeroHash, false
+	}
+
+	e, err := t.FindEntry(p)
+	if err != nil || !e.Mode.IsFile() {
+		return plumbing.ZeroHash, false
+	}
+
+	return e.Hash, true
+}
+
+// renamedFrom will look for the file that was renamed to the path
+// in the commit, amongst those removed from the parent.
+func renamedFrom(parent, c *object.Commit, p string) (string, bool) {
+	pt, err := parent.Tree()
+	if err != nil {
+		return "", false
+	}
+	ct, err := c.Tree()
+	if err != nil {
+		return "", false
+	}
+	f, err := ct.File(p)
+	if err != nil {
+		return "", false
+	}
+	content, err := f.Contents()
+	if err != nil {
+		return "", false
+	}
+
+	best, score := "", renameSimilarity
+	pt.Files().ForEach(func(x *object.File) error {
+		if _, err := ct.FindEntry(x.Name); err == nil {
+			return nil
+		}
+
+		if x.Hash == f.Hash {
+			best, score = x.Name, 1
+			return storer.ErrStop
+		}
+
+		old, err := x.Contents()
+		if err != nil {
+			return nil
+		}
+		if v := similarity(old, content); v >= score {
+			best, score = x.Name, v
+		}
+
+		return nil
+	})
+
+	return best, best != ""
+}
+
+// renames will pair each added file with the most similar removed
+// file, giving the rest as added or removed.
+func renames(a, b *object.Tree, removed, added []string) []autodocs.Change {
+	sort.Strings(added)
+	sort.Strings(removed)
+
+	used := map[string]bool{}
+	r := []autodocs.Change{}
+	for _, n := range added {
+		f, err := b.File(n)
+		if err != nil {
+			continue
+		}
+		content, _ := f.Contents()
+
+		best, score := "", renameSimilarity
+		for _, o := range removed {
+			x, err := a.File(o)
+			if used[o] || err != nil {
+				continue
+			}
+			if x.Hash == f.Hash {
+				best = o
+				break
+			}
+
+			old, err := x.Contents()
+			if err != nil {
+				continue
+			}
+			if v := similarity(old, content); v >= score {
+				best, score = o, v
+			}
+		}
+
+		if best == "" {
+			r = append(r, autodocs.Change{Kind: autodocs.ChangeAdded, To: n})
+			continue
+		}
+		used[best] = true
+		r = append(r, autodocs.Change{Kind: autodocs.ChangeRenamed, From: best, To: n})
+	}
+
+	for _, o := range removed {
+		if !used[o] {
+			r = append(r, autodocs.Change{Kind: autodocs.ChangeRemoved, From: o})
+		}
+	}
+
+	return r
+}
+
+// similarity gives the share of lines two files have in common.
+func similarity(a, b string) float64 {
+	x, y := strings.Split(a, "\n"), strings.Split(b, "\n")
+	if len(x) < len(y) {
+		x, y = y, x
+	}
+
+	seen := map[string]int{}
+	for _, l := range y {
+		seen[l]++
+	}
+
+	same := 0
+	for _, l := range x {
+		if seen[l] > 0 {
+			seen[l]--
+			same++
+		}
+	}
+
+	return float64(same) / float64(len(x))
+}
+
+// toCommit describes the commit for the file at the path.
+func toCommit(c *object.Commit, p string) autodocs.Commit {
+	return autodocs.Commit{
+		Author:  c.Author.Name,
+		Date:    c.Author.When,
+		Email:   c.Author.Email,
+		Message: strings.TrimSpace(c.Message),
+		Path:    p,
+		Sha:     c.Hash.String(),
+	}
+}
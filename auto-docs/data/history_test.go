@@ -0,0 +1,272 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	git "gopkg.in/src-d/go-git.v4"
+)
+
+type resolveStruct struct {
+	Ref    string
+	Exp    string
+	ExpErr bool
+	M      string
+}
+
+func TestResolve(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-history")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, err := git.PlainInit(filepath.Join(dir, "remote"), false)
+	assert.Nil(err)
+	day := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	one, err := commitFilesAt(r, map[string]string{"runbook.md": "# one"}, day)
+	assert.Nil(err)
+	two, err := commitFilesAt(r, map[string]string{"runbook.md": "# two"}, day.AddDate(0, 0, 1))
+	assert.Nil(err)
+	_, err = r.CreateTag("v1", one, &git.CreateTagOptions{Tagger: defaultSignature(), Message: "v1"})
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		History:   true,
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+
+	x := []resolveStruct{
+		{Ref: two.String(), Exp: two.String(), M: "A full sha should resolve."},
+		{Ref: one.String()[:8], Exp: one.String(), M: "An abbreviated sha should resolve."},
+		{Ref: "master", Exp: two.String(), M: "A branch should resolve."},
+		{Ref: "v1", Exp: one.String(), M: "An annotated tag should resolve to its commit."},
+		{Ref: "2020-06-01T18:00:00Z", Exp: one.String(), M: "A time should resolve to the commit before it."},
+		{Ref: "2020-06-03", Exp: two.String(), M: "A date should resolve to the commit before it."},
+		{Ref: "2020-05-01", ExpErr: true, M: "A date before any commit should error."},
+		{Ref: "nothing", ExpErr: true, M: "An unknown ref should error."},
+	}
+
+	for _, a := range x {
+		rev, err := s.Resolve(a.Ref)
+		assert.Equal(a.Exp, rev, a.M)
+		assert.Equal(a.ExpErr, err != nil, a.M)
+	}
+
+	b, err := s.ReadFile(one.String(), "runbook.md")
+	assert.Nil(err)
+	assert.Equal("# one", string(b), "A file should be read as it was.")
+
+	_, err = s.ReadFile(one.String(), "missing.md")
+	assert.Error(err, "A missing file should error.")
+
+	f, err := s.Files(two.String())
+	assert.Nil(err)
+	sort.Strings(f)
+	assert.Equal([]string{"runbook.md"}, f)
+}
+
+func TestPrepareHistory(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-history")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, one, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# one"})
+	assert.Nil(err)
+	_, err = commitFiles(r, map[string]string{"readme.md": "# two"})
+	assert.Nil(err)
+
+	g := autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}
+	s := &State{Git: g}
+	assert.Nil(s.Prepare())
+	_, err = s.Resolve(one.String())
+	assert.Error(err, "A shallow clone should not have history.")
+
+	g.History = true
+	s = &State{Git: g}
+	assert.Nil(s.Prepare())
+	rev, err := s.Resolve(one.String())
+	assert.Nil(err, "A shallow clone should be replaced when history is wanted.")
+	assert.Equal(one.String(), rev)
+}
+
+func TestLog(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-history")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, err := git.PlainInit(filepath.Join(dir, "remote"), false)
+	assert.Nil(err)
+	day := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	body := "# restart\n\none\ntwo\nthree\nfour\n"
+
+	add, err := commitFilesAt(r, map[string]string{"restart.md": body, "other.md": "# other"}, day)
+	assert.Nil(err)
+	other, err := commitFilesAt(r, map[string]string{"other.md": "# changed"}, day.Add(time.Hour))
+	assert.Nil(err)
+
+	// move the file and change it at the same time
+	w, err := r.Worktree()
+	assert.Nil(err)
+	_, err = w.Remove("restart.md")
+	assert.Nil(err)
+	move, err := commitFilesAt(r, map[string]string{"runbooks/restart.md": body + "five\n"}, day.Add(2*time.Hour))
+	assert.Nil(err)
+
+	edit, err := commitFilesAt(r, map[string]string{"runbooks/restart.md": body + "six\n"}, day.Add(3*time.Hour))
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		History:   true,
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+
+	l, err := s.Log(s.Revision(), "runbooks/restart.md")
+	assert.Nil(err)
+
+	shas, paths := []string{}, []string{}
+	for _, c := range l {
+		shas = append(shas, c.Sha)
+		paths = append(paths, c.Path)
+	}
+	assert.Equal([]string{edit.String(), move.String(), add.String()}, shas, "Only commits changing the file should be listed.")
+	assert.Equal([]string{"runbooks/restart.md", "runbooks/restart.md", "restart.md"}, paths, "Renames should be followed.")
+	assert.Equal("auto-docs", l[0].Author)
+	assert.True(day.Add(3 * time.Hour).Equal(l[0].Date))
+
+	m, err := s.LastModified(s.Revision(), nil)
+	assert.Nil(err)
+	assert.Equal(edit.String(), m["runbooks/restart.md"].Sha, "Last change should be the latest commit to the file.")
+	assert.Equal(other.String(), m["other.md"].Sha, "Other files should keep their own last change.")
+
+	m, err = s.LastModified(s.Revision(), []string{"other.md", "gone.md"})
+	assert.Nil(err)
+	assert.Equal(map[string]autodocs.Commit{"other.md": m["other.md"]}, m, "Only the files asked for should be given.")
+	assert.Equal(other.String(), m["other.md"].Sha, "A file asked for should have its last change.")
+
+	s.Git.History = false
+	_, err = s.Log(s.Revision(), "runbooks/restart.md")
+	assert.Equal(ErrNoHistory, err, "History should need enabling.")
+	_, err = s.LastModified(s.Revision(), nil)
+	assert.Equal(ErrNoHistory, err, "History should need enabling.")
+}
+
+type changesStruct struct {
+	From string
+	To   string
+	Exp  []autodocs.Change
+	M    string
+}
+
+func TestChanges(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-history")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, err := git.PlainInit(filepath.Join(dir, "remote"), false)
+	assert.Nil(err)
+	body := "# restart\n\none\ntwo\nthree\nfour\n"
+
+	one, err := commitFiles(r, map[string]string{"restart.md": body, "old.md": "# old", "edit.md": "# edit"})
+	assert.Nil(err)
+
+	w, err := r.Worktree()
+	assert.Nil(err)
+	_, err = w.Remove("restart.md")
+	assert.Nil(err)
+	_, err = w.Remove("old.md")
+	assert.Nil(err)
+	two, err := commitFiles(r, map[string]string{
+		"runbooks/restart.md": body + "five\n",
+		"edit.md":             "# edited",
+		"new.md":              "# new",
+	})
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		History:   true,
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+
+	x := []changesStruct{
+		{
+			From: one.String(),
+			To:   two.String(),
+			Exp: []autodocs.Change{
+				{Kind: autodocs.ChangeModified, From: "edit.md", To: "edit.md"},
+				{Kind: autodocs.ChangeAdded, To: "new.md"},
+				{Kind: autodocs.ChangeRemoved, From: "old.md"},
+				{Kind: autodocs.ChangeRenamed, From: "restart.md", To: "runbooks/restart.md"},
+			},
+			M: "Each kind of change should be found, with similar files renamed.",
+		},
+		{
+			From: two.String(),
+			To:   one.String(),
+			Exp: []autodocs.Change{
+				{Kind: autodocs.ChangeModified, From: "edit.md", To: "edit.md"},
+				{Kind: autodocs.ChangeRemoved, From: "new.md"},
+				{Kind: autodocs.ChangeAdded, To: "old.md"},
+				{Kind: autodocs.ChangeRenamed, From: "runbooks/restart.md", To: "restart.md"},
+			},
+			M: "Comparing backwards should reverse each change.",
+		},
+		{
+			From: two.String(),
+			To:   two.String(),
+			Exp:  []autodocs.Change{},
+			M:    "The same revision should have no changes.",
+		},
+	}
+
+	for _, a := range x {
+		c, err := s.Changes(a.From, a.To)
+		assert.Nil(err, a.M)
+		assert.Equal(a.Exp, c, a.M)
+	}
+
+	_, err = s.Changes("0000000000000000000000000000000000000000", two.String())
+	assert.Error(err, "An unknown revision should error.")
+}
+
+type similarityStruct struct {
+	A   string
+	B   string
+	Exp float64
+	M   string
+}
+
+func TestSimilarity(t *testing.T) {
+	assert := assert.New(t)
+	x := []similarityStruct{
+		{A: "a\nb\nc\nd", B: "a\nb\nc\nd", Exp: 1, M: "Identical content should be the same."},
+		{A: "a\nb\nc\nd", B: "a\nb\nx\ny", Exp: 0.5, M: "Half the lines should be half the same."},
+		{A: "a\nb", B: "a\nb\nc\nd", Exp: 0.5, M: "Added lines should lower similarity."},
+		{A: "a", B: "b", Exp: 0, M: "Different content should not be the same."},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, similarity(a.A, a.B), a.M)
+	}
+}
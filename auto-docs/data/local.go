@@ -0,0 +1,86 @@
+package data
+
+import (
+	"fmt"
+	"hash/fnv"
+	"os"
+	"path/filepath"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+)
+
+// Local is a Source for documentation that already exists within
+// a directory on disk, such as a mounted volume.
+type Local struct {
+	// Local contains the configuration for this source.
+	Local autodocs.Local
+
+	// Sum contains the fingerprint of the directory contents as
+	// of the last check.
+	Sum string
+}
+
+// Prepare will ensure the directory exists and take an initial
+// fingerprint of the content.
+func (l *Local) Prepare() error {
+	i, err := os.Stat(l.Local.Path)
+	if err != nil {
+		return fmt.Errorf("unable to read directory: %s", err)
+	}
+	if !i.IsDir() {
+		return fmt.Errorf("not a directory: %s", l.Local.Path)
+	}
+
+	l.Sum, err = fingerprint(l.Local.Path)
+	return err
+}
+
+// Fetch will re-fingerprint the directory, reporting a change if
+// any file has been added, removed or modified.
+func (l *Local) Fetch(t time.Time) (bool, error) {
+	sum, err := fingerprint(l.Local.Path)
+	if err != nil {
+		return false, err
+	}
+	if sum == l.Sum {
+		return false, nil
+	}
+
+	l.Sum = sum
+	return true, nil
+}
+
+// Revision gives the current fingerprint of the directory.
+func (l *Local) Revision() string {
+	return l.Sum
+}
+
+// FS gives the directory content is read from.
+func (l *Local) FS() billy.Filesystem {
+	return osfs.New(l.Local.Path)
+}
+
+// fingerprint will generate a hash of the name, size and
+// modification time of every file under the path.
+func fingerprint(p string) (string, error) {
+	h := fnv.New64a()
+	err := filepath.Walk(p, func(path string, i os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if i.IsDir() {
+			return nil
+		}
+
+		fmt.Fprintf(h, "%s:%d:%d\n", path, i.Size(), i.ModTime().UnixNano())
+		return nil
+	})
+	if err != nil {
+		return "", fmt.Errorf("unable to read directory: %s", err)
+	}
+
+	return fmt.Sprintf("%x", h.Sum64()), nil
+}
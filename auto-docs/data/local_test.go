@@ -0,0 +1,53 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalPrepare(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-local")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	l := &Local{Local: autodocs.Local{Path: filepath.Join(dir, "missing")}}
+	assert.Error(l.Prepare(), "A missing directory should error.")
+
+	l = &Local{Local: autodocs.Local{Path: dir}}
+	assert.Nil(l.Prepare(), "An existing directory should prepare.")
+	assert.NotEmpty(l.Revision(), "Preparing should fingerprint the directory.")
+	assert.Equal(dir, l.FS().Root(), "FS should be the configured path.")
+}
+
+func TestLocalFetch(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-local")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	l := &Local{Local: autodocs.Local{Path: dir}}
+	assert.Nil(l.Prepare())
+
+	changed, err := l.Fetch(time.Now())
+	assert.Nil(err)
+	assert.False(changed, "An untouched directory should not change.")
+
+	assert.Nil(ioutil.WriteFile(filepath.Join(dir, "page.md"), []byte("# page"), 0644))
+	rev := l.Revision()
+	changed, err = l.Fetch(time.Now())
+	assert.Nil(err)
+	assert.True(changed, "A new file should be a change.")
+	assert.NotEqual(rev, l.Revision(), "A change should give a new revision.")
+
+	assert.Nil(os.Remove(filepath.Join(dir, "page.md")))
+	changed, err = l.Fetch(time.Now())
+	assert.Nil(err)
+	assert.True(changed, "A removed file should be a change.")
+}
@@ -0,0 +1,130 @@
+package data
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// lock is a file alongside a local path that marks which process
+// is managing it, preventing two instances from sharing a checkout.
+type lock struct {
+	// path is the location of the lock file.
+	path string
+
+	// stale is how long a lock can go without being refreshed by
+	// a process on another host before it is taken over.
+	stale time.Duration
+}
+
+// acquire will take the lock for this process, failing if another
+// live process currently holds it.
+func (l *lock) acquire() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("unable to create lock: %s", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(l.path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = fmt.Fprintf(f, "%s %d\n", hostname(), os.Getpid())
+			f.Close()
+			return err
+		}
+		if !os.IsExist(err) {
+			return fmt.Errorf("unable to create lock: %s", err)
+		}
+
+		if owner, held := l.held(); held {
+			return fmt.Errorf("local path in use by %s", owner)
+		}
+		os.Remove(l.path)
+	}
+
+	return fmt.Errorf("unable to acquire lock: %s", l.path)
+}
+
+// held will check the existing lock file, giving the owner and
+// whether it belongs to another process that is still running.
+func (l *lock) held() (string, bool) {
+	i, err := os.Stat(l.path)
+	if err != nil {
+		return "", false
+	}
+	b, err := ioutil.ReadFile(l.path)
+	if err != nil {
+		return "", false
+	}
+
+	owner := strings.TrimSpace(string(b))
+	f := strings.Fields(owner)
+	if len(f) != 2 {
+		return owner, false
+	}
+	pid, err := strconv.Atoi(f[1])
+	if err != nil {
+		return owner, false
+	}
+
+	if f[0] != hostname() {
+		return owner, time.Since(i.ModTime()) < l.stale
+	}
+	if pid == os.Getpid() {
+		return owner, false
+	}
+
+	return owner, alive(pid)
+}
+
+// refresh will mark the lock as still in use.
+func (l *lock) refresh() {
+	now := time.Now()
+	os.Chtimes(l.path, now, now)
+}
+
+// release will remove the lock, so that another instance may take
+// over the local path straight away. A lock since taken over by
+// another process is left alone.
+func (l *lock) release() error {
+	b, err := ioutil.ReadFile(l.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return fmt.Errorf("unable to read lock: %s", err)
+	}
+
+	if strings.TrimSpace(string(b)) != fmt.Sprintf("%s %d", hostname(), os.Getpid()) {
+		return nil
+	}
+	if err := os.Remove(l.path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("unable to release lock: %s", err)
+	}
+
+	return nil
+}
+
+// hostname gives the name of this host, for identifying the owner
+// of a lock across a shared filesystem.
+func hostname() string {
+	h, err := os.Hostname()
+	if err != nil {
+		return "unknown"
+	}
+
+	return h
+}
+
+// alive checks whether a process with the pid is running.
+func alive(pid int) bool {
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+
+	return p.Signal(syscall.Signal(0)) == nil
+}
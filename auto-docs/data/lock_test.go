@@ -0,0 +1,95 @@
+package data
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type lockStruct struct {
+	Owner  string
+	Age    time.Duration
+	ExpErr bool
+	M      string
+}
+
+func TestLockAcquire(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-lock")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	x := []lockStruct{
+		{
+			Owner:  "",
+			ExpErr: false,
+			M:      "No existing lock should be acquired.",
+		},
+		{
+			Owner:  fmt.Sprintf("%s %d", hostname(), os.Getpid()),
+			ExpErr: false,
+			M:      "A lock from this process should be acquired.",
+		},
+		{
+			Owner:  fmt.Sprintf("%s %d", hostname(), os.Getppid()),
+			ExpErr: true,
+			M:      "A lock from another running process should fail.",
+		},
+		{
+			Owner:  "elsewhere 1",
+			Age:    time.Second,
+			ExpErr: true,
+			M:      "A fresh lock from another host should fail.",
+		},
+		{
+			Owner:  "elsewhere 1",
+			Age:    time.Hour,
+			ExpErr: false,
+			M:      "A stale lock from another host should be acquired.",
+		},
+		{
+			Owner:  "garbage",
+			ExpErr: false,
+			M:      "An unreadable lock should be acquired.",
+		},
+	}
+
+	for i, a := range x {
+		l := &lock{path: filepath.Join(dir, fmt.Sprint(i), "repo.lock"), stale: time.Minute}
+		if a.Owner != "" {
+			assert.Nil(os.MkdirAll(filepath.Dir(l.path), 0755))
+			assert.Nil(ioutil.WriteFile(l.path, []byte(a.Owner+"\n"), 0644))
+
+			then := time.Now().Add(-a.Age)
+			assert.Nil(os.Chtimes(l.path, then, then))
+		}
+
+		err := l.acquire()
+		assert.Equal(a.ExpErr, err != nil, a.M)
+	}
+}
+
+func TestLockRelease(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-lock")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	l := &lock{path: filepath.Join(dir, "repo.lock"), stale: time.Minute}
+	assert.Nil(l.release(), "Releasing a missing lock should succeed.")
+
+	assert.Nil(l.acquire())
+	assert.Nil(l.release())
+	_, err = os.Stat(l.path)
+	assert.True(os.IsNotExist(err), "A released lock should be removed.")
+
+	assert.Nil(ioutil.WriteFile(l.path, []byte("elsewhere 1\n"), 0644))
+	assert.Nil(l.release())
+	_, err = os.Stat(l.path)
+	assert.Nil(err, "A lock taken over by another process should be left alone.")
+}
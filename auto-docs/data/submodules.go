@@ -0,0 +1,123 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"path"
+	"path/filepath"
+	"strings"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+// updateSubmodules will check out each submodule at the commit
+// recorded by the repository, recursively, when enabled. Submodule
+// URLs relative to the repository are resolved against its remote.
+func (s *State) updateSubmodules() error {
+	if !s.Git.Submodules {
+		return nil
+	}
+
+	w, err := s.g.Worktree()
+	if err != nil {
+		return fmt.Errorf("unable to get worktree: %s", err)
+	}
+
+	err = s.remote(s.cloneTimeout(), func(ctx context.Context) error {
+		return s.updateWorktree(ctx, w, s.Git.URI, int(git.DefaultSubmoduleRecursionDepth))
+	})
+	if err != nil {
+		return fmt.Errorf("unable to update submodules: %s", err)
+	}
+
+	return nil
+}
+
+// updateWorktree will check out each submodule of the worktree, whose
+// remote is at base, and then their own submodules down to the depth.
+// Each submodule is updated with the credentials for its own URL.
+func (s *State) updateWorktree(ctx context.Context, w *git.Worktree, base string, depth int) error {
+	subs, err := w.Submodules()
+	if err != nil {
+		return fmt.Errorf("unable to read submodules: %s", err)
+	}
+
+	for _, x := range subs {
+		c := x.Config()
+		c.URL = submoduleURL(base, c.URL)
+
+		auth, err := submoduleAuth(s.Git, c.URL)
+		if err != nil {
+			return err
+		}
+
+		err = x.UpdateContext(ctx, &git.SubmoduleUpdateOptions{
+			Auth:              auth,
+			Init:              true,
+			RecurseSubmodules: git.NoRecurseSubmodules,
+		})
+		if err != nil {
+			return fmt.Errorf("unable to update %s: %s", c.Name, err)
+		}
+
+		if depth <= 1 {
+			continue
+		}
+
+		r, err := x.Repository()
+		if err != nil {
+			return fmt.Errorf("unable to open %s: %s", c.Name, err)
+		}
+		sw, err := r.Worktree()
+		if err != nil {
+			return fmt.Errorf("unable to get worktree of %s: %s", c.Name, err)
+		}
+		if err := s.updateWorktree(ctx, sw, c.URL, depth-1); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// submoduleAuth gives the credentials to fetch a submodule from the
+// URL. Those of the repository are only sent to the same host over the
+// same scheme, so a submodule anywhere else is fetched anonymously.
+func submoduleAuth(g autodocs.Git, u string) (transport.AuthMethod, error) {
+	a, err := transport.NewEndpoint(g.URI)
+	if err != nil {
+		return nil, nil
+	}
+	b, err := transport.NewEndpoint(u)
+	if err != nil {
+		return nil, nil
+	}
+
+	if a.Protocol != b.Protocol || a.Host != b.Host || a.Port != b.Port {
+		return nil, nil
+	}
+
+	return authMethod(g)
+}
+
+// submoduleURL resolves the URL of a submodule, which may be given
+// relative to the URL of the repository it is within.
+func submoduleURL(base, u string) string {
+	if !strings.HasPrefix(u, "./") && !strings.HasPrefix(u, "../") {
+		return u
+	}
+
+	// a remote URL is treated as a directory, as git does
+	if x, err := url.Parse(base); err == nil && x.Scheme != "" && x.Host != "" {
+		x.Path = path.Join(x.Path, u)
+		return x.String()
+	}
+	if i := strings.Index(base, ":"); i > 0 && !strings.Contains(base[:i], "/") && !filepath.IsAbs(base) {
+		return base[:i+1] + path.Join(base[i+1:], u)
+	}
+
+	return filepath.Join(base, filepath.FromSlash(u))
+}
@@ -0,0 +1,173 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/filemode"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/index"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+func TestSubmodules(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	style, h, err := initRepo(filepath.Join(dir, "style"), map[string]string{"glossary.md": "# glossary"})
+	assert.Nil(err)
+	r, _, err := initRepo(filepath.Join(dir, "docs"), map[string]string{"readme.md": "# readme"})
+	assert.Nil(err)
+	assert.Nil(commitSubmodule(r, "style", "../style", h))
+
+	s := &State{Git: autodocs.Git{
+		Branch:     "master",
+		LocalPath:  filepath.Join(dir, "local"),
+		Submodules: true,
+		URI:        filepath.Join(dir, "docs"),
+	}}
+	assert.Nil(s.Prepare(), "Cloning with submodules should succeed.")
+
+	b, err := ioutil.ReadFile(filepath.Join(s.Git.LocalPath, "style", "glossary.md"))
+	assert.Nil(err, "Submodule content should be checked out.")
+	assert.Equal("# glossary", string(b))
+
+	h, err = commitFiles(style, map[string]string{"glossary.md": "# terms"})
+	assert.Nil(err)
+	assert.Nil(commitSubmodule(r, "style", "../style", h))
+
+	changed, err := s.Fetch(time.Now())
+	assert.Nil(err)
+	assert.True(changed)
+
+	b, err = ioutil.ReadFile(filepath.Join(s.Git.LocalPath, "style", "glossary.md"))
+	assert.Nil(err, "Submodule content should remain after fetching.")
+	assert.Equal("# terms", string(b), "Submodules should follow the recorded commit.")
+
+	o := &State{Git: autodocs.Git{
+		Branch:    "master",
+		LocalPath: filepath.Join(dir, "plain"),
+		URI:       filepath.Join(dir, "docs"),
+	}}
+	assert.Nil(o.Prepare())
+	_, err = os.Stat(filepath.Join(o.Git.LocalPath, "style", "glossary.md"))
+	assert.True(os.IsNotExist(err), "Submodules should only be checked out when enabled.")
+}
+
+type submoduleURLStruct struct {
+	Base string
+	URL  string
+	Exp  string
+	M    string
+}
+
+func TestSubmoduleURL(t *testing.T) {
+	assert := assert.New(t)
+	x := []submoduleURLStruct{
+		{
+			Base: "https://example.com/org/docs.git",
+			URL:  "https://example.com/org/style.git",
+			Exp:  "https://example.com/org/style.git",
+			M:    "An absolute URL should be unchanged.",
+		},
+		{
+			Base: "https://example.com/org/docs.git",
+			URL:  "../style.git",
+			Exp:  "https://example.com/org/style.git",
+			M:    "A relative URL should resolve against the remote.",
+		},
+		{
+			Base: "https://example.com/org/docs",
+			URL:  "./style",
+			Exp:  "https://example.com/org/docs/style",
+			M:    "A URL relative to the repository should be within it.",
+		},
+		{
+			Base: "git@example.com:org/docs.git",
+			URL:  "../style.git",
+			Exp:  "git@example.com:org/style.git",
+			M:    "A relative URL should resolve against an scp-like remote.",
+		},
+		{
+			Base: "/srv/git/docs",
+			URL:  "../style",
+			Exp:  "/srv/git/style",
+			M:    "A relative URL should resolve against a local remote.",
+		},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, submoduleURL(a.Base, a.URL), a.M)
+	}
+}
+
+type submoduleAuthStruct struct {
+	URI     string
+	URL     string
+	ExpAuth transport.AuthMethod
+	M       string
+}
+
+func TestSubmoduleAuth(t *testing.T) {
+	assert := assert.New(t)
+	token := &http.BasicAuth{Username: tokenUser, Password: "abc123"}
+	x := []submoduleAuthStruct{
+		{URI: "https://example.com/a/docs.git", URL: "https://example.com/a/style.git", ExpAuth: token, M: "The same host and scheme should share credentials."},
+		{URI: "https://example.com/a/docs.git", URL: "https://other.com/a/style.git", ExpAuth: nil, M: "Credentials should not be sent to another host."},
+		{URI: "https://example.com/a/docs.git", URL: "http://example.com/a/style.git", ExpAuth: nil, M: "Credentials should not be sent over another scheme."},
+		{URI: "https://example.com/a/docs.git", URL: "https://example.com:8443/a/style.git", ExpAuth: nil, M: "Credentials should not be sent to another port."},
+		{URI: "git@example.com:a/docs.git", URL: "https://example.com/a/style.git", ExpAuth: nil, M: "An SSH repository should fetch HTTPS submodules anonymously."},
+		{URI: "https://example.com/a/docs.git", URL: "not a url\x00", ExpAuth: nil, M: "An unparsable URL should be fetched anonymously."},
+	}
+
+	for _, a := range x {
+		auth, err := submoduleAuth(autodocs.Git{URI: a.URI, Token: "abc123", SSHKey: "/does/not/exist"}, a.URL)
+
+		assert.Nil(err, a.M)
+		assert.Equal(a.ExpAuth, auth, a.M)
+	}
+}
+
+// commitSubmodule will record a submodule at the path within the
+// repository, checked out at the commit.
+func commitSubmodule(r *git.Repository, p, u string, h plumbing.Hash) error {
+	w, err := r.Worktree()
+	if err != nil {
+		return err
+	}
+
+	m := "[submodule \"" + p + "\"]\n\tpath = " + p + "\n\turl = " + u + "\n"
+	if err := ioutil.WriteFile(filepath.Join(w.Filesystem.Root(), ".gitmodules"), []byte(m), 0644); err != nil {
+		return err
+	}
+	if _, err := w.Add(".gitmodules"); err != nil {
+		return err
+	}
+
+	idx, err := r.Storer.Index()
+	if err != nil {
+		return err
+	}
+	e, err := idx.Entry(p)
+	if err == index.ErrEntryNotFound {
+		e = idx.Add(p)
+	} else if err != nil {
+		return err
+	}
+	e.Hash, e.Mode = h, filemode.Submodule
+	if err := r.Storer.SetIndex(idx); err != nil {
+		return err
+	}
+
+	_, err = w.Commit("update submodule", &git.CommitOptions{Author: defaultSignature()})
+	return err
+}
@@ -0,0 +1,55 @@
+package data
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport/client"
+	githttp "gopkg.in/src-d/go-git.v4/plumbing/transport/http"
+)
+
+// httpClient tracks the client installed for go-git to use with
+// HTTP(S) remotes. go-git keeps a single client per protocol for the
+// whole process, so it is shared by every source.
+var httpClient struct {
+	sync.Mutex
+
+	// installed is set once any source has installed the client.
+	installed bool
+
+	// timeout is the wait for each response allowed by the client,
+	// where zero is no limit.
+	timeout time.Duration
+}
+
+// useTransport will have go-git wait no longer than the Timeout of
+// the source for each response from an HTTP(S) remote. go-git makes
+// some requests without the context of the operation, so an
+// unresponsive remote would otherwise hold up every later operation
+// against it. As the client is shared, the longest wait of any source
+// is kept.
+func useTransport(g autodocs.Git) {
+	u := strings.ToLower(g.URI)
+	if !strings.HasPrefix(u, "http://") && !strings.HasPrefix(u, "https://") {
+		return
+	}
+
+	httpClient.Lock()
+	defer httpClient.Unlock()
+
+	d := time.Duration(g.Timeout) * time.Millisecond
+	if httpClient.installed && (httpClient.timeout == 0 || (d != 0 && d <= httpClient.timeout)) {
+		return
+	}
+
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.ResponseHeaderTimeout = d
+
+	c := githttp.NewClient(&http.Client{Transport: t})
+	client.InstallProtocol("http", c)
+	client.InstallProtocol("https", c)
+	httpClient.installed, httpClient.timeout = true, d
+}
@@ -0,0 +1,132 @@
+package data
+
+import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/storer"
+)
+
+// verifyDepth is the number of commits back from the remote head
+// that are searched for one signed by a trusted key.
+const verifyDepth = 100
+
+// errUntrusted is given when there is no revision signed by a
+// trusted key to be checked out, so that the checkout is left as is.
+var errUntrusted = errors.New("unable to verify commits")
+
+// Verified is implemented by any Source that only serves revisions
+// signed by a trusted key.
+type Verified interface {
+	// Unverified gives the latest revision that was refused for
+	// not being signed by a trusted key, or empty if there is none.
+	Unverified() string
+}
+
+// Unverified gives the remote head when it was refused for not
+// being signed by a key within the keyring.
+func (s *State) Unverified() string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.unverified
+}
+
+// target gives the commit to check out for the remote head. With a
+// keyring configured and the head not signed by a trusted key, the
+// latest signed commit before it is used when it follows on from the
+// current checkout, otherwise the current checkout is kept. Going back
+// to an older signed commit would roll the site back after a force
+// push.
+func (s *State) target(head plumbing.Hash) (plumbing.Hash, error) {
+	if s.Git.Keyring == "" {
+		return head, nil
+	}
+
+	keys, err := s.keyring()
+	if err != nil {
+		return plumbing.ZeroHash, err
+	}
+
+	c, err := s.g.CommitObject(head)
+	if err != nil {
+		return plumbing.ZeroHash, fmt.Errorf("unable to retrieve commit: %s", err)
+	}
+	if trusted(c, keys) {
+		s.unverified = ""
+		return head, nil
+	}
+
+	if s.unverified != head.String() {
+		log.Println("refusing commit not signed by a trusted key:", head.String())
+	}
+	s.unverified = head.String()
+
+	var found *object.Commit
+	n := 0
+	_ = object.NewCommitPreorderIter(c, nil, nil).ForEach(func(c *object.Commit) error {
+		if n++; n > verifyDepth {
+			return storer.ErrStop
+		}
+		if trusted(c, keys) {
+			found = c
+			return storer.ErrStop
+		}
+
+		return nil
+	})
+	if found != nil && s.follows(found) {
+		return found.Hash, nil
+	}
+
+	// shallow history ends early, and a rewritten history may only
+	// lead to older commits, so keep the checkout as it is
+	if s.Sha != "" {
+		if c, err := s.g.CommitObject(plumbing.NewHash(s.Sha)); err == nil && trusted(c, keys) {
+			return c.Hash, nil
+		}
+	}
+
+	return plumbing.ZeroHash, fmt.Errorf("%w: no commit signed by a trusted key up to %s", errUntrusted, head.String())
+}
+
+// follows checks if the commit is the current checkout or comes after
+// it, which is always the case when nothing has been checked out yet.
+func (s *State) follows(c *object.Commit) bool {
+	if s.Sha == "" || c.Hash.String() == s.Sha {
+		return true
+	}
+
+	cur, err := s.g.CommitObject(plumbing.NewHash(s.Sha))
+	if err != nil {
+		return false
+	}
+
+	ok, err := cur.IsAncestor(c)
+	return err == nil && ok
+}
+
+// keyring gives the armored public keys that commits must be signed
+// by, read fresh each time so that keys may be rotated.
+func (s *State) keyring() (string, error) {
+	b, err := ioutil.ReadFile(s.Git.Keyring)
+	if err != nil {
+		return "", fmt.Errorf("%w: unable to read keyring: %s", errUntrusted, err)
+	}
+
+	return string(b), nil
+}
+
+// trusted checks if the commit is signed by a key within the keys.
+func trusted(c *object.Commit, keys string) bool {
+	if c.PGPSignature == "" {
+		return false
+	}
+
+	_, err := c.Verify(keys)
+	return err == nil
+}
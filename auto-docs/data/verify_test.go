@@ -0,0 +1,192 @@
+package data
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/openpgp"
+	"golang.org/x/crypto/openpgp/armor"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestFetchVerified(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	key, other := signingKey(t, "docs"), signingKey(t, "other")
+	keyring := filepath.Join(dir, "keyring.asc")
+	assert.Nil(ioutil.WriteFile(keyring, armoredKey(t, key), 0644))
+
+	r, err := git.PlainInit(filepath.Join(dir, "remote"), false)
+	assert.Nil(err)
+	signed, err := commitSigned(r, map[string]string{"readme.md": "# readme"}, key)
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		Keyring:   keyring,
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare(), "A signed head should be checked out.")
+	assert.Equal(signed.String(), s.Revision())
+	assert.Empty(s.Unverified())
+
+	for _, k := range []*openpgp.Entity{nil, other} {
+		h, err := commitSigned(r, map[string]string{"runbook.md": "# run me"}, k)
+		assert.Nil(err)
+
+		changed, err := s.Fetch(time.Now())
+		assert.Nil(err, "An untrusted head should not fail the fetch.")
+		assert.False(changed, "An untrusted head should not be checked out.")
+		assert.Equal(signed.String(), s.Revision(), "The last signed commit should be kept.")
+		assert.Equal(h.String(), s.Unverified(), "The untrusted head should be reported.")
+
+		_, err = os.Stat(filepath.Join(s.Git.LocalPath, "runbook.md"))
+		assert.True(os.IsNotExist(err), "Untrusted content should not be served.")
+	}
+
+	h, err := commitSigned(r, map[string]string{"runbook.md": "# run me safely"}, key)
+	assert.Nil(err)
+
+	changed, err := s.Fetch(time.Now())
+	assert.Nil(err)
+	assert.True(changed, "A signed head should be checked out.")
+	assert.Equal(h.String(), s.Revision())
+	assert.Empty(s.Unverified(), "A signed head should clear the report.")
+	assert.FileExists(filepath.Join(s.Git.LocalPath, "runbook.md"))
+
+	// a fresh clone with history falls back to the latest signed commit
+	head, err := commitSigned(r, map[string]string{"other.md": "# other"}, nil)
+	assert.Nil(err)
+
+	o := &State{Git: autodocs.Git{
+		Branch:    "master",
+		History:   true,
+		Keyring:   keyring,
+		LocalPath: filepath.Join(dir, "history"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(o.Prepare(), "A signed commit within the history should be checked out.")
+	assert.Equal(h.String(), o.Revision())
+	assert.Equal(head.String(), o.Unverified())
+	_, err = os.Stat(filepath.Join(o.Git.LocalPath, "other.md"))
+	assert.True(os.IsNotExist(err), "Untrusted content should not be checked out.")
+
+	// with no signed commit available nothing is checked out
+	o = &State{Git: autodocs.Git{
+		Branch:    "master",
+		Keyring:   keyring,
+		LocalPath: filepath.Join(dir, "shallow"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Error(o.Prepare(), "A shallow clone of an untrusted head should fail.")
+	assert.Empty(o.Revision())
+	assert.Equal(head.String(), o.Unverified())
+	_, err = os.Stat(filepath.Join(o.Git.LocalPath, "readme.md"))
+	assert.True(os.IsNotExist(err), "Nothing should be checked out.")
+
+	_, err = o.Fetch(time.Now())
+	assert.Error(err, "Fetching should continue to refuse the head.")
+	assert.Empty(o.Revision())
+}
+
+func TestFetchVerifiedForcePush(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-data")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	key := signingKey(t, "docs")
+	keyring := filepath.Join(dir, "keyring.asc")
+	assert.Nil(ioutil.WriteFile(keyring, armoredKey(t, key), 0644))
+
+	r, err := git.PlainInit(filepath.Join(dir, "remote"), false)
+	assert.Nil(err)
+	base, err := commitSigned(r, map[string]string{"readme.md": "# readme"}, key)
+	assert.Nil(err)
+	h, err := commitSigned(r, map[string]string{"runbook.md": "# run me"}, key)
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		History:   true,
+		Keyring:   keyring,
+		LocalPath: filepath.Join(dir, "local"),
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+	assert.Equal(h.String(), s.Revision())
+
+	// rewrite the remote history so that the latest signed commit is
+	// older than the checkout
+	w, err := r.Worktree()
+	assert.Nil(err)
+	assert.Nil(w.Reset(&git.ResetOptions{Commit: base, Mode: git.HardReset}))
+	head, err := commitSigned(r, map[string]string{"other.md": "# other"}, nil)
+	assert.Nil(err)
+
+	changed, err := s.Fetch(time.Now())
+	assert.Nil(err, "An untrusted rewrite should not fail the fetch.")
+	assert.False(changed, "An untrusted rewrite should not roll the checkout back.")
+	assert.Equal(h.String(), s.Revision(), "The current checkout should be kept.")
+	assert.Equal(head.String(), s.Unverified(), "The untrusted head should be reported.")
+	assert.FileExists(filepath.Join(s.Git.LocalPath, "runbook.md"), "Content should not be rolled back.")
+
+	// a signed commit after the checkout is still followed
+	assert.Nil(w.Reset(&git.ResetOptions{Commit: h, Mode: git.HardReset}))
+	next, err := commitSigned(r, map[string]string{"next.md": "# next"}, key)
+	assert.Nil(err)
+	_, err = commitSigned(r, map[string]string{"other.md": "# other"}, nil)
+	assert.Nil(err)
+
+	changed, err = s.Fetch(time.Now())
+	assert.Nil(err)
+	assert.True(changed, "A signed commit after the checkout should be checked out.")
+	assert.Equal(next.String(), s.Revision())
+}
+
+// signingKey will generate a key for signing commits.
+func signingKey(t *testing.T, name string) *openpgp.Entity {
+	e, err := openpgp.NewEntity(name, "", name+"@example.com", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return e
+}
+
+// armoredKey gives the armored public key of the entity.
+func armoredKey(t *testing.T, e *openpgp.Entity) []byte {
+	var b bytes.Buffer
+	w, err := armor.Encode(&b, openpgp.PublicKeyType, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := e.Serialize(w); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	return b.Bytes()
+}
+
+// commitSigned will commit the provided files, signed by the key
+// when one is given.
+func commitSigned(r *git.Repository, files map[string]string, key *openpgp.Entity) (plumbing.Hash, error) {
+	return commitFilesWith(r, files, &git.CommitOptions{
+		Author:  defaultSignature(),
+		SignKey: key,
+	})
+}
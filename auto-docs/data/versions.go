@@ -0,0 +1,332 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/config"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+	"gopkg.in/src-d/go-git.v4/plumbing/object"
+	"gopkg.in/src-d/go-git.v4/plumbing/transport"
+)
+
+// Versions gives each further branch and tag of the repository
+// that matches the configured patterns.
+func (s *State) Versions() []autodocs.Version {
+	return s.versions
+}
+
+// VersionFS gives the content for a version, writing the tree for
+// the commit out to disk if it has not been already. With Memory
+// set, the tree is written out to memory instead.
+func (s *State) VersionFS(v autodocs.Version) (billy.Filesystem, error) {
+	if fs, ok := s.trees[v.Sha]; ok {
+		return fs, nil
+	}
+
+	fs, err := s.extract(v)
+	if err != nil {
+		return nil, fmt.Errorf("unable to extract %s: %s", v.Name, err)
+	}
+
+	if s.trees == nil {
+		s.trees = map[string]billy.Filesystem{}
+	}
+	s.trees[v.Sha] = fs
+
+	return fs, nil
+}
+
+// extract will write out the tree for the commit of a version.
+func (s *State) extract(v autodocs.Version) (billy.Filesystem, error) {
+	d := filepath.Join(s.versionsPath(), v.Sha)
+	if _, err := os.Stat(d); err == nil && !s.Git.Memory {
+		return osfs.New(d), nil
+	}
+
+	c, err := s.commitOf(plumbing.NewHash(v.Sha))
+	if err != nil {
+		return nil, err
+	}
+	t, err := c.Tree()
+	if err != nil {
+		return nil, err
+	}
+
+	if s.Git.Memory {
+		fs := memfs.New()
+		return fs, t.Files().ForEach(func(f *object.File) error {
+			return extractFile(fs, f)
+		})
+	}
+
+	if err := os.MkdirAll(s.versionsPath(), 0755); err != nil {
+		return nil, err
+	}
+	tmp, err := ioutil.TempDir(s.versionsPath(), ".extract")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	fs := osfs.New(tmp)
+	err = t.Files().ForEach(func(f *object.File) error {
+		return extractFile(fs, f)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.Rename(tmp, d); err != nil {
+		return nil, err
+	}
+
+	return osfs.New(d), nil
+}
+
+// versioned checks if any further versions have been configured.
+func (s *State) versioned() bool {
+	return len(s.Git.Branches) > 0 || len(s.Git.Tags) > 0
+}
+
+// versionsPath gives the location versions are extracted into.
+func (s *State) versionsPath() string {
+	return s.Git.LocalPath + ".versions"
+}
+
+// listVersions will ask the remote for every reference, giving
+// the names of those that match the configured patterns.
+func (s *State) listVersions(auth transport.AuthMethod) (map[plumbing.ReferenceName]bool, error) {
+	if !s.versioned() {
+		return nil, nil
+	}
+
+	r, err := s.g.Remote(git.DefaultRemoteName)
+	if err != nil {
+		return nil, fmt.Errorf("unable to find remote: %s", err)
+	}
+
+	var refs []*plumbing.Reference
+	err = s.remote(s.Git.Timeout, func(ctx context.Context) error {
+		x, err := r.List(&git.ListOptions{Auth: auth})
+		refs = x
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list versions: %s", err)
+	}
+
+	names := map[plumbing.ReferenceName]bool{}
+	for _, x := range refs {
+		if _, ok := s.versionOf(x.Name(), true); ok {
+			names[x.Name()] = true
+		}
+	}
+
+	return names, nil
+}
+
+// versionSpecs gives the refspecs to fetch each listed version.
+func (s *State) versionSpecs(names map[plumbing.ReferenceName]bool) []config.RefSpec {
+	r := []config.RefSpec{}
+	for n := range names {
+		dst := n
+		if n.IsBranch() {
+			dst = plumbing.NewRemoteReferenceName(git.DefaultRemoteName, n.Short())
+		}
+
+		r = append(r, config.RefSpec(fmt.Sprintf("+%s:%s", n, dst)))
+	}
+
+	return r
+}
+
+// updateVersions will remove local references for versions that
+// are no longer on the remote, then reload the known versions.
+func (s *State) updateVersions(names map[plumbing.ReferenceName]bool) error {
+	if names == nil {
+		return nil
+	}
+
+	stale := []plumbing.ReferenceName{}
+	err := s.references(func(r *plumbing.Reference, remote plumbing.ReferenceName) {
+		if !names[remote] {
+			stale = append(stale, r.Name())
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, n := range stale {
+		if err := s.g.Storer.RemoveReference(n); err != nil {
+			return fmt.Errorf("unable to remove version: %s", err)
+		}
+	}
+
+	return s.loadVersions()
+}
+
+// loadVersions will find each version amongst the local references.
+// With a keyring configured, only versions signed by a trusted key
+// are kept.
+func (s *State) loadVersions() error {
+	keys := ""
+	if s.Git.Keyring != "" {
+		var err error
+		if keys, err = s.keyring(); err != nil {
+			return err
+		}
+	}
+
+	v := []autodocs.Version{}
+	err := s.references(func(r *plumbing.Reference, remote plumbing.ReferenceName) {
+		x, _ := s.versionOf(remote, false)
+
+		if c, err := s.commitOf(r.Hash()); err == nil && (keys == "" || trusted(c, keys)) {
+			x.Sha = c.Hash.String()
+			v = append(v, x)
+		}
+	})
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(v, func(i, j int) bool {
+		if v[i].Kind != v[j].Kind {
+			return v[i].Kind < v[j].Kind
+		}
+
+		return v[i].Name < v[j].Name
+	})
+	s.versions = v
+
+	return nil
+}
+
+// references will call f for every local reference that is a
+// version, along with the name of the reference on the remote.
+func (s *State) references(f func(*plumbing.Reference, plumbing.ReferenceName)) error {
+	i, err := s.g.References()
+	if err != nil {
+		return fmt.Errorf("unable to read references: %s", err)
+	}
+
+	prefix := "refs/remotes/" + git.DefaultRemoteName + "/"
+	return i.ForEach(func(r *plumbing.Reference) error {
+		n := r.Name()
+		if strings.HasPrefix(n.String(), prefix) {
+			n = plumbing.NewBranchReferenceName(strings.TrimPrefix(n.String(), prefix))
+		}
+
+		if _, ok := s.versionOf(n, true); ok {
+			f(r, n)
+		}
+
+		return nil
+	})
+}
+
+// versionOf describes the remote reference as a version, checking
+// whether it matches the configured patterns when asked to.
+func (s *State) versionOf(n plumbing.ReferenceName, check bool) (autodocs.Version, bool) {
+	switch {
+	case n.IsBranch() && n.Short() != s.Git.Branch:
+		return autodocs.Version{Kind: "branch", Name: n.Short()}, !check || matchAny(s.Git.Branches, n.Short())
+
+	case n.IsTag() && !strings.HasSuffix(n.String(), "^{}"):
+		return autodocs.Version{Kind: "tag", Name: n.Short()}, !check || matchAny(s.Git.Tags, n.Short())
+	}
+
+	return autodocs.Version{}, false
+}
+
+// commitOf gives the commit for a hash, peeling annotated tags.
+func (s *State) commitOf(h plumbing.Hash) (*object.Commit, error) {
+	if t, err := s.g.TagObject(h); err == nil {
+		return t.Commit()
+	}
+
+	return s.g.CommitObject(h)
+}
+
+// pruneRoots will remove any extracted version no longer known.
+func (s *State) pruneRoots() {
+	keep := map[string]bool{}
+	for _, v := range s.versions {
+		keep[v.Sha] = true
+	}
+	for sha := range s.trees {
+		if !keep[sha] {
+			delete(s.trees, sha)
+		}
+	}
+	if s.Git.Memory {
+		return
+	}
+
+	i, err := ioutil.ReadDir(s.versionsPath())
+	if err != nil {
+		return
+	}
+	for _, x := range i {
+		if !keep[x.Name()] {
+			os.RemoveAll(filepath.Join(s.versionsPath(), x.Name()))
+		}
+	}
+}
+
+// Follows checks if the repository is kept up to date with a
+// reference on the remote, being either the branch or a further
+// branch or tag matching the configured patterns.
+func Follows(g autodocs.Git, ref string) bool {
+	n := plumbing.ReferenceName(ref)
+	if n.IsBranch() && n.Short() == g.Branch {
+		return true
+	}
+
+	_, ok := (&State{Git: g}).versionOf(n, true)
+	return ok
+}
+
+// matchAny checks the name against each of the patterns.
+func matchAny(patterns []string, n string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, n); ok {
+			return true
+		}
+	}
+
+	return false
+}
+
+// extractFile will write the contents of a file within a tree out
+// to the filesystem.
+func extractFile(fs billy.Filesystem, f *object.File) error {
+	r, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	w, err := fs.Create(f.Name)
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	_, err = io.Copy(w, r)
+	return err
+}
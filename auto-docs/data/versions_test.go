@@ -0,0 +1,132 @@
+package data
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	git "gopkg.in/src-d/go-git.v4"
+	"gopkg.in/src-d/go-git.v4/plumbing"
+)
+
+func TestVersions(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "auto-docs-versions")
+	assert.Nil(err)
+	defer os.RemoveAll(dir)
+
+	r, v1, err := initRepo(filepath.Join(dir, "remote"), map[string]string{"readme.md": "# one"})
+	assert.Nil(err)
+	_, err = r.CreateTag("v1.0", v1, &git.CreateTagOptions{
+		Tagger:  defaultSignature(),
+		Message: "v1.0",
+	})
+	assert.Nil(err)
+	_, err = r.CreateTag("other", v1, nil)
+	assert.Nil(err)
+	assert.Nil(r.Storer.SetReference(plumbing.NewHashReference("refs/heads/release/1", v1)))
+	assert.Nil(r.Storer.SetReference(plumbing.NewHashReference("refs/heads/feature", v1)))
+
+	head, err := commitFiles(r, map[string]string{"readme.md": "# two"})
+	assert.Nil(err)
+
+	s := &State{Git: autodocs.Git{
+		Branch:    "master",
+		Branches:  []string{"release/*"},
+		LocalPath: filepath.Join(dir, "local"),
+		Tags:      []string{"v*"},
+		URI:       filepath.Join(dir, "remote"),
+	}}
+	assert.Nil(s.Prepare())
+	assert.Equal(head.String(), s.Revision(), "The branch should be checked out.")
+	assert.Equal(
+		[]autodocs.Version{
+			{Kind: "branch", Name: "release/1", Sha: v1.String()},
+			{Kind: "tag", Name: "v1.0", Sha: v1.String()},
+		},
+		s.Versions(),
+		"Only matching branches and tags should be versions.",
+	)
+
+	fs, err := s.VersionFS(s.Versions()[1])
+	assert.Nil(err)
+	root := fs.Root()
+	b, err := ioutil.ReadFile(filepath.Join(root, "readme.md"))
+	assert.Nil(err)
+	assert.Equal("# one", string(b), "A version should have the content at its commit.")
+
+	again, err := s.VersionFS(s.Versions()[1])
+	assert.Nil(err)
+	assert.True(fs == again, "A version should only be extracted once.")
+
+	// move the versions on the remote
+	assert.Nil(r.Storer.RemoveReference("refs/heads/release/1"))
+	_, err = r.CreateTag("v2.0", head, nil)
+	assert.Nil(err)
+
+	changed, err := s.Fetch(time.Now())
+	assert.Nil(err)
+	assert.False(changed, "New versions should not change the branch.")
+	assert.Equal(
+		[]autodocs.Version{
+			{Kind: "tag", Name: "v1.0", Sha: v1.String()},
+			{Kind: "tag", Name: "v2.0", Sha: head.String()},
+		},
+		s.Versions(),
+		"Versions should follow the remote.",
+	)
+
+	_, err = s.Fetch(time.Now())
+	assert.Nil(err)
+	_, err = os.Stat(root)
+	assert.Nil(err, "Extracted versions still in use should be kept.")
+}
+
+type matchStruct struct {
+	Patterns []string
+	Name     string
+	Exp      bool
+	M        string
+}
+
+func TestMatchAny(t *testing.T) {
+	assert := assert.New(t)
+	x := []matchStruct{
+		{Patterns: []string{"v*"}, Name: "v1.2.3", Exp: true, M: "Prefix patterns should match."},
+		{Patterns: []string{"release/*"}, Name: "release/1.0", Exp: true, M: "Nested patterns should match."},
+		{Patterns: []string{"main", "v*"}, Name: "main", Exp: true, M: "Exact names should match."},
+		{Patterns: []string{"v*"}, Name: "other", Exp: false, M: "Other names should not match."},
+		{Patterns: nil, Name: "v1", Exp: false, M: "No patterns should not match."},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, matchAny(a.Patterns, a.Name), a.M)
+	}
+}
+
+type followsStruct struct {
+	Ref string
+	Exp bool
+	M   string
+}
+
+func TestFollows(t *testing.T) {
+	assert := assert.New(t)
+	g := autodocs.Git{Branch: "master", Branches: []string{"release/*"}, Tags: []string{"v*"}}
+	x := []followsStruct{
+		{Ref: "refs/heads/master", Exp: true, M: "The branch should be followed."},
+		{Ref: "refs/heads/release/1.0", Exp: true, M: "A branch matching a pattern should be followed."},
+		{Ref: "refs/tags/v1.2.3", Exp: true, M: "A tag matching a pattern should be followed."},
+		{Ref: "refs/heads/feature", Exp: false, M: "Another branch should not be followed."},
+		{Ref: "refs/tags/nightly", Exp: false, M: "Another tag should not be followed."},
+		{Ref: "master", Exp: false, M: "A name that is not a reference should not be followed."},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, Follows(g, a.Ref), a.M)
+	}
+}
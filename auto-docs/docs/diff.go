@@ -0,0 +1,238 @@
+package docs
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/sergi/go-diff/diffmatchpatch"
+)
+
+// diffContext is the number of unchanged lines shown around each
+// change in a unified diff.
+const diffContext = 3
+
+// htmlToken splits rendered content into tags, words and space.
+var htmlToken = regexp.MustCompile(`<[^>]*>|[^<\s]+|\s+`)
+
+// edit is a run of tokens that are equal, deleted or inserted.
+type edit struct {
+	op     diffmatchpatch.Operation
+	tokens []string
+}
+
+// Unified will give a unified diff of the markdown content of a
+// page between two revisions, with each side named for its file.
+// A side without a name is shown as /dev/null, as it was added or
+// removed.
+func Unified(fromName, toName string, a, b []byte) string {
+	type line struct {
+		op   diffmatchpatch.Operation
+		text string
+		x, y int
+	}
+
+	lines := []line{}
+	x, y := 1, 1
+	for _, e := range diffTokens(splitLines(string(a)), splitLines(string(b))) {
+		for _, t := range e.tokens {
+			lines = append(lines, line{e.op, t, x, y})
+			if e.op != diffmatchpatch.DiffInsert {
+				x++
+			}
+			if e.op != diffmatchpatch.DiffDelete {
+				y++
+			}
+		}
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(lines); {
+		if lines[i].op == diffmatchpatch.DiffEqual {
+			i++
+			continue
+		}
+
+		// extend the hunk while changes are close enough to share context
+		start, end := i-diffContext, i
+		if start < 0 {
+			start = 0
+		}
+		for j := i; j < len(lines) && j <= end+2*diffContext+1; j++ {
+			if lines[j].op != diffmatchpatch.DiffEqual {
+				end = j
+			}
+		}
+		end += diffContext + 1
+		if end > len(lines) {
+			end = len(lines)
+		}
+
+		if sb.Len() == 0 {
+			fmt.Fprintf(&sb, "--- %s\n+++ %s\n", diffName("a/", fromName), diffName("b/", toName))
+		}
+
+		dx, dy := 0, 0
+		for _, l := range lines[start:end] {
+			if l.op != diffmatchpatch.DiffInsert {
+				dx++
+			}
+			if l.op != diffmatchpatch.DiffDelete {
+				dy++
+			}
+		}
+		fmt.Fprintf(&sb, "@@ -%s +%s @@\n",
+			hunkRange(lines[start].x, dx), hunkRange(lines[start].y, dy))
+
+		for _, l := range lines[start:end] {
+			switch l.op {
+			case diffmatchpatch.DiffDelete:
+				sb.WriteString("-")
+			case diffmatchpatch.DiffInsert:
+				sb.WriteString("+")
+			default:
+				sb.WriteString(" ")
+			}
+			sb.WriteString(l.text)
+			if !strings.HasSuffix(l.text, "\n") {
+				sb.WriteString("\n\\ No newline at end of file\n")
+			}
+		}
+
+		i = end
+	}
+
+	return sb.String()
+}
+
+// WordDiff will render the markdown content of a page at two
+// revisions and give the HTML of the later one, with words that
+// were removed wrapped in del and words that were added in ins.
+func WordDiff(a, b []byte) string {
+	from := htmlToken.FindAllString(NewPage("/", a).Content, -1)
+	to := htmlToken.FindAllString(NewPage("/", b).Content, -1)
+
+	var sb strings.Builder
+	for _, e := range diffTokens(from, to) {
+		if e.op == diffmatchpatch.DiffEqual {
+			sb.WriteString(strings.Join(e.tokens, ""))
+			continue
+		}
+
+		tag := "ins"
+		if e.op == diffmatchpatch.DiffDelete {
+			tag = "del"
+		}
+
+		// markup is kept from the later revision only, and space is
+		// left outside, so that only words are ever wrapped
+		open, space := false, ""
+		for _, t := range e.tokens {
+			switch {
+			case strings.TrimSpace(t) == "":
+				space += t
+				continue
+			case strings.HasPrefix(t, "<"):
+				if open {
+					sb.WriteString("</" + tag + ">")
+					open = false
+				}
+				sb.WriteString(space)
+				if e.op == diffmatchpatch.DiffInsert {
+					sb.WriteString(t)
+				}
+			case open:
+				sb.WriteString(space + t)
+			default:
+				sb.WriteString(space + "<" + tag + ">" + t)
+				open = true
+			}
+			space = ""
+		}
+		if open {
+			sb.WriteString("</" + tag + ">")
+		}
+		sb.WriteString(space)
+	}
+
+	return sb.String()
+}
+
+// diffTokens will give the shortest set of edits to turn one list of
+// tokens in to the other. Each distinct token is given its own rune
+// so that the comparison can be made on whole tokens.
+func diffTokens(a, b []string) []edit {
+	ids := map[string]rune{}
+	names := []string{}
+	encode := func(x []string) []rune {
+		r := make([]rune, len(x))
+		for i, t := range x {
+			id, ok := ids[t]
+			if !ok {
+				// surrogates are not valid runes in a string
+				id = rune(len(names) + 1)
+				if id >= 0xd800 {
+					id += 0x800
+				}
+				ids[t] = id
+				names = append(names, t)
+			}
+			r[i] = id
+		}
+		return r
+	}
+	x, y := encode(a), encode(b)
+
+	d := diffmatchpatch.New()
+	d.DiffTimeout = 0
+
+	r := []edit{}
+	for _, v := range d.DiffMainRunes(x, y, false) {
+		e := edit{op: v.Type}
+		for _, id := range v.Text {
+			if id >= 0xe000 {
+				id -= 0x800
+			}
+			e.tokens = append(e.tokens, names[id-1])
+		}
+		r = append(r, e)
+	}
+
+	return r
+}
+
+// splitLines will break content in to lines, keeping the newline
+// at the end of each.
+func splitLines(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+
+	r := strings.SplitAfter(s, "\n")
+	if r[len(r)-1] == "" {
+		r = r[:len(r)-1]
+	}
+
+	return r
+}
+
+// diffName gives the name of one side of a diff.
+func diffName(prefix, n string) string {
+	if n == "" {
+		return "/dev/null"
+	}
+
+	return prefix + n
+}
+
+// hunkRange describes the lines covered by a hunk on one side.
+func hunkRange(start, n int) string {
+	if n == 0 {
+		return fmt.Sprintf("%d,0", start-1)
+	}
+	if n == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+
+	return fmt.Sprintf("%d,%d", start, n)
+}
@@ -0,0 +1,116 @@
+package docs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type unifiedStruct struct {
+	From string
+	To   string
+	Exp  string
+	M    string
+}
+
+func TestUnified(t *testing.T) {
+	assert := assert.New(t)
+	long := "1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n"
+
+	x := []unifiedStruct{
+		{
+			From: "# one\n",
+			To:   "# one\n",
+			Exp:  "",
+			M:    "The same content should have no diff.",
+		},
+		{
+			From: "# one\n\nfirst\n",
+			To:   "# one\n\nsecond\n",
+			Exp:  "--- a/old.md\n+++ b/new.md\n@@ -1,3 +1,3 @@\n # one\n \n-first\n+second\n",
+			M:    "A changed line should be shown with context.",
+		},
+		{
+			From: "",
+			To:   "# new\n",
+			Exp:  "--- a/old.md\n+++ b/new.md\n@@ -0,0 +1 @@\n+# new\n",
+			M:    "An added file should be all insertions.",
+		},
+		{
+			From: "# old\n",
+			To:   "",
+			Exp:  "--- a/old.md\n+++ /dev/null\n@@ -1 +0,0 @@\n-# old\n",
+			M:    "A removed file should have no later name.",
+		},
+		{
+			From: "# one",
+			To:   "# two",
+			Exp:  "--- a/old.md\n+++ b/new.md\n@@ -1 +1 @@\n-# one\n\\ No newline at end of file\n+# two\n\\ No newline at end of file\n",
+			M:    "A missing final newline should be marked.",
+		},
+		{
+			From: long,
+			To:   "0\n" + long[:len(long)-3] + "twelve\n",
+			Exp: "--- a/old.md\n+++ b/new.md\n" +
+				"@@ -1,3 +1,4 @@\n+0\n 1\n 2\n 3\n" +
+				"@@ -9,4 +10,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+			M: "Changes far apart should be in separate hunks.",
+		},
+		{
+			From: long,
+			To:   "0\n1\n2\n3\n4\n5\n6\nseven\n8\n9\n10\n11\n12\n",
+			Exp: "--- a/old.md\n+++ b/new.md\n" +
+				"@@ -1,10 +1,11 @@\n+0\n 1\n 2\n 3\n 4\n 5\n 6\n-7\n+seven\n 8\n 9\n 10\n",
+			M: "Changes within twice the context should share a hunk.",
+		},
+	}
+
+	for _, a := range x {
+		to := "new.md"
+		if a.To == "" {
+			to = ""
+		}
+		assert.Equal(a.Exp, Unified("old.md", to, []byte(a.From), []byte(a.To)), a.M)
+	}
+}
+
+type wordDiffStruct struct {
+	From string
+	To   string
+	Exp  string
+	M    string
+}
+
+func TestWordDiff(t *testing.T) {
+	assert := assert.New(t)
+	x := []wordDiffStruct{
+		{
+			From: "# title\n\nsome words here\n",
+			To:   "# title\n\nsome words here\n",
+			Exp:  "<h1>title</h1>\n<p>some words here</p>\n",
+			M:    "The same content should have no changes marked.",
+		},
+		{
+			From: "# title\n\nsome old words\n",
+			To:   "# title\n\nsome new words\n",
+			Exp:  "<h1>title</h1>\n<p>some <del>old</del><ins>new</ins> words</p>\n",
+			M:    "A changed word should be marked.",
+		},
+		{
+			From: "# title\n\nsome words\n",
+			To:   "# title\n\nsome **bold** words\n",
+			Exp:  "<h1>title</h1>\n<p>some <strong><ins>bold</ins></strong> words</p>\n",
+			M:    "Added markup should be kept, with only the text marked.",
+		},
+		{
+			From: "# title\n\nsome **bold** words\n",
+			To:   "# title\n\nsome words\n",
+			Exp:  "<h1>title</h1>\n<p>some <del>bold</del> words</p>\n",
+			M:    "Removed markup should be dropped, with only the text marked.",
+		},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, WordDiff([]byte(a.From), []byte(a.To)), a.M)
+	}
+}
@@ -0,0 +1,249 @@
+package docs
+
+import (
+	"bufio"
+	"bytes"
+	"log"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"gopkg.in/src-d/go-git.v4/plumbing/format/gitignore"
+)
+
+// IgnoreFile is the name of a file within a source that lists
+// patterns, in the style of .gitignore, for files not to serve.
+const IgnoreFile = ".autodocsignore"
+
+// landingFiles lists the names of files that are served as the page
+// of the directory they are within, in order of priority.
+var landingFiles = []string{"_index.md", "index.md", "readme.md"}
+
+// Filter decides which files within a source are served as pages,
+// and the path that each is served from.
+type Filter struct {
+	// exclude matches files that are not served.
+	exclude gitignore.Matcher
+
+	// include matches the only files that are served, when set.
+	include gitignore.Matcher
+
+	// landings keeps the file served as the page of each directory
+	// that has one, by the directory.
+	landings map[string]string
+
+	// clashes holds each file that shares the path it is served
+	// from with another, of which only one is served.
+	clashes map[string]bool
+
+	// shadowed holds each file that is not served, as another file
+	// is served from the same path.
+	shadowed map[string]bool
+
+	// root is the directory within the source pages are served
+	// from, split in to each part.
+	root []string
+}
+
+// NewFilter will build a Filter for the files within a source,
+// using read to load the content of any ignore files amongst them.
+// Each file is a slash separated path relative to the source.
+func NewFilter(c autodocs.Index, files []string, read func(string) ([]byte, error)) *Filter {
+	f := &Filter{root: split(filepath.ToSlash(c.Root))}
+
+	// patterns closer to a file take priority, then the config
+	ignores := []string{}
+	for _, x := range files {
+		if path.Base(x) == IgnoreFile {
+			ignores = append(ignores, x)
+		}
+	}
+	sort.Slice(ignores, func(i, j int) bool {
+		a, b := strings.Count(ignores[i], "/"), strings.Count(ignores[j], "/")
+		return a < b || (a == b && ignores[i] < ignores[j])
+	})
+
+	ps := []gitignore.Pattern{}
+	for _, x := range ignores {
+		b, err := read(x)
+		if err != nil {
+			log.Println("unable to read ignore file:", x)
+			continue
+		}
+
+		ps = append(ps, patterns(b, split(path.Dir(x)))...)
+	}
+	for _, x := range c.Exclude {
+		ps = append(ps, gitignore.ParsePattern(x, f.root))
+	}
+	f.exclude = gitignore.NewMatcher(ps)
+
+	if len(c.Include) > 0 {
+		ps = []gitignore.Pattern{}
+		for _, x := range c.Include {
+			ps = append(ps, gitignore.ParsePattern(x, f.root))
+		}
+		f.include = gitignore.NewMatcher(ps)
+	}
+
+	f.landings = map[string]string{}
+	for _, x := range files {
+		d := path.Dir(x)
+		if y, ok := f.landings[d]; landing(x) < 0 || !f.serves(x) || (ok && landing(y) <= landing(x)) {
+			continue
+		}
+		f.landings[d] = x
+	}
+	f.clash(files)
+
+	return f
+}
+
+// clash will find the files that are served from the same path, such
+// as guides.md beside guides/README.md, leaving only one of them to be
+// served. A landing file takes priority, otherwise the first by name.
+func (f *Filter) clash(files []string) {
+	f.clashes, f.shadowed = map[string]bool{}, map[string]bool{}
+
+	l := append([]string{}, files...)
+	sort.Strings(l)
+
+	held := map[string]string{}
+	for _, x := range l {
+		k, ok := f.Key("", x)
+		if !ok {
+			continue
+		}
+
+		y, taken := held[k]
+		if !taken {
+			held[k] = x
+			continue
+		}
+		if f.landings[path.Dir(x)] == x {
+			held[k], x, y = x, y, x
+		}
+
+		log.Println("not serving", x, "as", y, "is served from the same path")
+		f.clashes[x], f.clashes[y], f.shadowed[x] = true, true, true
+	}
+}
+
+// Key gives the path that a file is served from under the mount,
+// or false when the file is not served. A landing file is served
+// from the path of its directory.
+func (f *Filter) Key(m, file string) (string, bool) {
+	if !f.serves(file) || f.shadowed[file] {
+		return "", false
+	}
+
+	p := split(file)[len(f.root):]
+	if f.landings[path.Dir(file)] == file {
+		p = p[:len(p)-1]
+	}
+
+	return Key(m, filepath.FromSlash(path.Join(p...))), true
+}
+
+// serves will check if a file is served as a page.
+func (f *Filter) serves(file string) bool {
+	if !strings.HasSuffix(file, ".md") || !f.within(file) {
+		return false
+	}
+
+	p := split(file)
+	if f.exclude.Match(p, false) {
+		return false
+	}
+
+	return f.include == nil || f.include.Match(p, false)
+}
+
+// skips checks if nothing within a directory is served, as it is
+// excluded, so that it need not be walked.
+func (f *Filter) skips(dir string) bool {
+	return f.within(dir) && f.exclude.Match(split(dir), true)
+}
+
+// Dir gives the path that a directory is served from under the
+// mount, or false when it is outside of the root.
+func (f *Filter) Dir(m, dir string) (string, bool) {
+	if !f.within(dir) {
+		return "", false
+	}
+
+	p := split(dir)
+	return Key(m, filepath.FromSlash(path.Join(p[len(f.root):]...))), true
+}
+
+// landing gives the priority of a file as the page of its directory,
+// lowest first, or -1 when it is not a landing file.
+func landing(file string) int {
+	n := strings.ToLower(path.Base(file))
+	for i, x := range landingFiles {
+		if n == x {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// within will check if a path is inside the root that pages are
+// served from.
+func (f *Filter) within(p string) bool {
+	x := split(p)
+	if len(x) < len(f.root) {
+		return false
+	}
+
+	for i, r := range f.root {
+		if x[i] != r {
+			return false
+		}
+	}
+
+	return true
+}
+
+// toward will check if a directory is either inside the root, or
+// is one of the directories leading to it.
+func (f *Filter) toward(dir string) bool {
+	x := split(dir)
+	for i := 0; i < len(x) && i < len(f.root); i++ {
+		if x[i] != f.root[i] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// patterns will read each pattern from the content of an ignore
+// file found within the directory.
+func patterns(b []byte, domain []string) []gitignore.Pattern {
+	r := []gitignore.Pattern{}
+	s := bufio.NewScanner(bytes.NewReader(b))
+	for s.Scan() {
+		l := s.Text()
+		if strings.HasPrefix(l, "#") || strings.TrimSpace(l) == "" {
+			continue
+		}
+
+		r = append(r, gitignore.ParsePattern(l, domain))
+	}
+
+	return r
+}
+
+// split will break a slash separated path in to each part.
+func split(p string) []string {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return []string{}
+	}
+
+	return strings.Split(p, "/")
+}
@@ -0,0 +1,214 @@
+package docs
+
+import (
+	"fmt"
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+)
+
+type filterStruct struct {
+	Index   autodocs.Index
+	Ignores map[string]string
+	Exp     map[string]string
+	M       string
+}
+
+func TestFilter(t *testing.T) {
+	assert := assert.New(t)
+	files := []string{
+		"README.md",
+		"CHANGELOG.md",
+		"main.go",
+		"docs/index.md",
+		"docs/guides/Setup.md",
+		"docs/guides/draft.md",
+		"docs/fixtures/sample.md",
+		"vendor/lib/README.md",
+		"node_modules/pkg/readme.md",
+	}
+
+	x := []filterStruct{
+		{
+			Exp: map[string]string{
+				"README.md":                  "/",
+				"CHANGELOG.md":               "/CHANGELOG",
+				"docs/index.md":              "/docs",
+				"docs/guides/Setup.md":       "/docs/guides/Setup",
+				"docs/guides/draft.md":       "/docs/guides/draft",
+				"docs/fixtures/sample.md":    "/docs/fixtures/sample",
+				"vendor/lib/README.md":       "/vendor/lib",
+				"node_modules/pkg/readme.md": "/node_modules/pkg",
+			},
+			M: "Every markdown file should be served by default.",
+		},
+		{
+			Index: autodocs.Index{Root: "docs/"},
+			Exp: map[string]string{
+				"docs/index.md":           "/",
+				"docs/guides/Setup.md":    "/guides/Setup",
+				"docs/guides/draft.md":    "/guides/draft",
+				"docs/fixtures/sample.md": "/fixtures/sample",
+			},
+			M: "Only files under the root should be served, relative to it.",
+		},
+		{
+			Index: autodocs.Index{Exclude: []string{"vendor/", "node_modules", "CHANGELOG.md", "**/fixtures"}},
+			Exp: map[string]string{
+				"README.md":            "/",
+				"docs/index.md":        "/docs",
+				"docs/guides/Setup.md": "/docs/guides/Setup",
+				"docs/guides/draft.md": "/docs/guides/draft",
+			},
+			M: "Excluded files should not be served.",
+		},
+		{
+			Index: autodocs.Index{Root: "docs", Include: []string{"guides/"}, Exclude: []string{"draft.md"}},
+			Exp: map[string]string{
+				"docs/guides/Setup.md": "/guides/Setup",
+			},
+			M: "Only included files should be served, less those excluded.",
+		},
+		{
+			Ignores: map[string]string{
+				".autodocsignore":      "# generated\n/vendor\nnode_modules/\n*.md\n!docs/\n",
+				"docs/.autodocsignore": "fixtures/\n",
+			},
+			Exp: map[string]string{
+				"docs/index.md":        "/docs",
+				"docs/guides/Setup.md": "/docs/guides/Setup",
+				"docs/guides/draft.md": "/docs/guides/draft",
+			},
+			M: "Ignore files should apply to their own directory, deepest last.",
+		},
+		{
+			Index:   autodocs.Index{Root: "docs", Exclude: []string{"!fixtures/"}},
+			Ignores: map[string]string{"docs/.autodocsignore": "fixtures/\nguides/\n"},
+			Exp: map[string]string{
+				"docs/index.md":           "/",
+				"docs/fixtures/sample.md": "/fixtures/sample",
+			},
+			M: "Config should take priority over ignore files.",
+		},
+	}
+
+	for _, a := range x {
+		all := append([]string{}, files...)
+		for n := range a.Ignores {
+			all = append(all, n)
+		}
+		f := NewFilter(a.Index, all, func(p string) ([]byte, error) {
+			if c, ok := a.Ignores[p]; ok {
+				return []byte(c), nil
+			}
+			return nil, fmt.Errorf("no file %s", p)
+		})
+
+		r := map[string]string{}
+		for _, n := range all {
+			if k, ok := f.Key("", n); ok {
+				r[n] = k
+			}
+		}
+		assert.Equal(a.Exp, r, a.M)
+	}
+}
+
+func TestFilterLanding(t *testing.T) {
+	assert := assert.New(t)
+	files := []string{
+		"README.md",
+		"guides/README.md",
+		"guides/index.md",
+		"guides/setup.md",
+		"api/_index.md",
+		"api/Index.md",
+		"drafts/README.md",
+		"drafts/plan.md",
+	}
+	f := NewFilter(autodocs.Index{Exclude: []string{"drafts/README.md"}}, files, nil)
+
+	r := map[string]string{}
+	for _, n := range files {
+		if k, ok := f.Key("ops", n); ok {
+			r[n] = k
+		}
+	}
+	assert.Equal(map[string]string{
+		"README.md":        "/ops",
+		"guides/README.md": "/ops/guides/README",
+		"guides/index.md":  "/ops/guides",
+		"guides/setup.md":  "/ops/guides/setup",
+		"api/_index.md":    "/ops/api",
+		"api/Index.md":     "/ops/api/Index",
+		"drafts/plan.md":   "/ops/drafts/plan",
+	}, r, "Only the first landing file served in each directory should be served from it.")
+}
+
+func TestFilterClash(t *testing.T) {
+	assert := assert.New(t)
+	files := []string{
+		"guides.md",
+		"guides/README.md",
+		"run book.md",
+		"run-book.md",
+		"setup.md",
+	}
+	f := NewFilter(autodocs.Index{}, files, nil)
+
+	r := map[string]string{}
+	for _, n := range files {
+		if k, ok := f.Key("ops", n); ok {
+			r[n] = k
+		}
+	}
+	assert.Equal(map[string]string{
+		"guides/README.md": "/ops/guides",
+		"run book.md":      "/ops/run-book",
+		"setup.md":         "/ops/setup",
+	}, r, "Only one file should be served from each path, preferring a landing page.")
+	assert.Equal(
+		map[string]bool{"guides.md": true, "guides/README.md": true, "run book.md": true, "run-book.md": true},
+		f.clashes,
+		"Each file sharing a path should be recorded.",
+	)
+}
+
+func TestFilterSkips(t *testing.T) {
+	assert := assert.New(t)
+	f := NewFilter(autodocs.Index{Root: "docs", Exclude: []string{"vendor/", "**/fixtures"}}, nil, nil)
+
+	for d, exp := range map[string]bool{
+		"docs":                 false,
+		"docs/guides":          false,
+		"docs/vendor":          true,
+		"docs/guides/fixtures": true,
+		"vendor":               false,
+	} {
+		assert.Equal(exp, f.skips(d), "Only excluded directories within the root should be skipped: "+d)
+	}
+}
+
+func TestMountIndex(t *testing.T) {
+	assert := assert.New(t)
+	s := &Store{
+		Dirs:  []*Dir{},
+		Pages: map[string]*autodocs.Page{},
+	}
+
+	s.Mount("ops", osfs.New(getTestMarkdownDir()), autodocs.Index{Root: "first", Exclude: []string{"two.md"}})
+	assert.Equal([]string{"/ops/one"}, keys(s.Pages), "Only files allowed by the index should be loaded.")
+
+	s.Annotate("ops", map[string]autodocs.Commit{"first/one.md": {Author: "Ann"}})
+	assert.Equal("Ann", s.Pages["/ops/one"].Author, "Files should be annotated by their path within the source.")
+}
+
+func keys(m map[string]*autodocs.Page) []string {
+	r := []string{}
+	for k := range m {
+		r = append(r, k)
+	}
+	return r
+}
@@ -0,0 +1,80 @@
+package docs
+
+import (
+	"bytes"
+	"fmt"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter holds the known keys of the YAML block that may open
+// a markdown file, between lines of ---.
+type frontMatter struct {
+	Aliases     []string `yaml:"aliases"`
+	Description string   `yaml:"description"`
+	Hidden      bool     `yaml:"hidden"`
+	Owner       string   `yaml:"owner"`
+	Tags        []string `yaml:"tags"`
+	Title       string   `yaml:"title"`
+	Weight      int      `yaml:"weight"`
+}
+
+// frontMatterKeys lists the keys of frontMatter, which are left out
+// of the page meta.
+var frontMatterKeys = []string{"aliases", "description", "hidden", "owner", "tags", "title", "weight"}
+
+// splitFrontMatter will separate any front matter from the rest of
+// the markdown content, giving false when there is none.
+func splitFrontMatter(f []byte) ([]byte, []byte, bool) {
+	b := bytes.TrimPrefix(f, []byte("\xef\xbb\xbf"))
+	nl := bytes.IndexByte(b, '\n')
+	if nl < 0 || string(bytes.TrimRight(b[:nl], " \r")) != "---" {
+		return nil, f, false
+	}
+
+	for i := nl + 1; i < len(b); {
+		end := bytes.IndexByte(b[i:], '\n')
+		next := len(b)
+		if end >= 0 {
+			next = i + end + 1
+		}
+
+		if l := string(bytes.TrimRight(b[i:next], " \r\n")); l == "---" || l == "..." {
+			return b[nl+1 : i], b[next:], true
+		}
+		i = next
+	}
+
+	return nil, f, false
+}
+
+// parseFrontMatter will set the metadata of the page from the front
+// matter, keeping any unknown keys within the page meta.
+func parseFrontMatter(pg *autodocs.Page, y []byte) error {
+	fm := frontMatter{}
+	if err := yaml.Unmarshal(y, &fm); err != nil {
+		return fmt.Errorf("unable to parse front matter: %s", err)
+	}
+
+	meta := map[string]interface{}{}
+	if err := yaml.Unmarshal(y, &meta); err != nil {
+		return fmt.Errorf("unable to parse front matter: %s", err)
+	}
+	for _, k := range frontMatterKeys {
+		delete(meta, k)
+	}
+	if len(meta) > 0 {
+		pg.Meta = meta
+	}
+
+	pg.Aliases = fm.Aliases
+	pg.Description = fm.Description
+	pg.Hidden = fm.Hidden
+	pg.Owner = fm.Owner
+	pg.Tags = fm.Tags
+	pg.Title = fm.Title
+	pg.Weight = fm.Weight
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package docs
+
+import (
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+)
+
+type frontMatterStruct struct {
+	Inp string
+	Exp *autodocs.Page
+	M   string
+}
+
+func TestFrontMatter(t *testing.T) {
+	assert := assert.New(t)
+	x := []frontMatterStruct{
+		{
+			Inp: "# restart\n",
+			Exp: &autodocs.Page{Name: "restart", Content: "<h1>restart</h1>\n", Title: "restart"},
+			M:   "A page without front matter should be rendered as is.",
+		},
+		{
+			Inp: "---\n" +
+				"title: Restarting the API\n" +
+				"description: How to restart safely\n" +
+				"weight: 10\n" +
+				"tags: [api, runbook]\n" +
+				"hidden: true\n" +
+				"aliases:\n  - /ops/restart-api\n" +
+				"owner: platform-team\n" +
+				"severity: high\n" +
+				"review:\n  every: 90d\n" +
+				"---\n" +
+				"# restart\n",
+			Exp: &autodocs.Page{
+				Name:        "restart",
+				Content:     "<h1>restart</h1>\n",
+				Title:       "Restarting the API",
+				Description: "How to restart safely",
+				Weight:      10,
+				Tags:        []string{"api", "runbook"},
+				Hidden:      true,
+				Aliases:     []string{"/ops/restart-api"},
+				Owner:       "platform-team",
+				Meta: map[string]interface{}{
+					"severity": "high",
+					"review":   map[string]interface{}{"every": "90d"},
+				},
+			},
+			M: "Front matter should be parsed and stripped.",
+		},
+		{
+			Inp: "\xef\xbb\xbf---\r\ntitle: Windows\r\n...\r\nbody\r\n",
+			Exp: &autodocs.Page{Name: "restart", Content: "<p>body</p>\n", Title: "Windows"},
+			M:   "A byte order mark, CRLF and a ... close should be allowed.",
+		},
+		{
+			Inp: "---\ntitle: [unclosed\n---\nbody\n",
+			Exp: &autodocs.Page{Name: "restart", Content: "<hr />\n<h2>title: [unclosed</h2>\n<p>body</p>\n", Title: "Restart"},
+			M:   "Invalid front matter should be left in place.",
+		},
+		{
+			Inp: "---\ntitle: never closed\n\nbody\n",
+			Exp: &autodocs.Page{Name: "restart", Content: "<hr />\n<p>title: never closed</p>\n<p>body</p>\n", Title: "Restart"},
+			M:   "An unclosed block should not be front matter.",
+		},
+		{
+			Inp: "text\n\n---\ntitle: later\n---\n",
+			Exp: &autodocs.Page{Name: "restart", Content: "<p>text</p>\n<hr />\n<h2>title: later</h2>\n", Title: "Restart"},
+			M:   "Front matter should only be found at the start.",
+		},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, NewPage("/ops/restart", []byte(a.Inp)), a.M)
+	}
+}
@@ -0,0 +1,58 @@
+package docs
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"strings"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+)
+
+// listDirs will build a listing page for each Dir, and all of their
+// children, within the directory served from the path that has no
+// page of its own. Each of these is then led to its listing.
+func (s *Store) listDirs(d []*Dir, p string, r map[string]*autodocs.Page) {
+	for _, x := range d {
+		k := path.Join(p, x.Name)
+		s.listDirs(x.Children, k, r)
+
+		if x.Children != nil && x.Path == "" {
+			r[k] = s.listing(x.Name, x.Text, x.Children)
+			x.Path = k
+		}
+	}
+}
+
+// listRoot will build a listing page of the top level of the
+// structure of pages, when there is no page served from the root.
+func (s *Store) listRoot(r map[string]*autodocs.Page) {
+	if _, ok := s.Pages["/"]; !ok && len(s.Dirs) > 0 {
+		r["/"] = s.listing("", "", s.Dirs)
+	}
+}
+
+// listing gives a page linking to each Dir, by its title and along
+// with the description of the page it leads to. Hidden pages are left
+// out.
+func (s *Store) listing(n, t string, d []*Dir) *autodocs.Page {
+	b := strings.Builder{}
+	if t != "" {
+		fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(t))
+	}
+
+	b.WriteString("<ul>\n")
+	for _, x := range d {
+		if x.Children == nil && !s.listed(x.Path) {
+			continue
+		}
+		fmt.Fprintf(&b, `<li><a href="%s">%s</a>`, html.EscapeString(x.Path), html.EscapeString(x.Text))
+		if pg, ok := s.Pages[x.Path]; ok && pg.Description != "" {
+			fmt.Fprintf(&b, " - %s", html.EscapeString(pg.Description))
+		}
+		b.WriteString("</li>\n")
+	}
+	b.WriteString("</ul>\n")
+
+	return &autodocs.Page{Name: n, Title: t, Content: b.String()}
+}
@@ -0,0 +1,90 @@
+package docs
+
+import (
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+func TestListing(t *testing.T) {
+	assert := assert.New(t)
+	fs := memfs.New()
+	for n, c := range map[string]string{
+		"guides/setup.md":         "---\ndescription: Getting <set> up.\n---\n# Setup",
+		"guides/deploy.md":        "# Deploy",
+		"guides/draft.md":         "---\nhidden: true\n---\n# Draft",
+		"guides/advanced/tune.md": "# Tune",
+		"api/README.md":           "# The API",
+	} {
+		assert.Nil(util.WriteFile(fs, n, []byte(c), 0644))
+	}
+
+	s := NewStore()
+	s.Mount("", fs, autodocs.Index{})
+	assert.Equal(5, len(s.Pages), "Listings should not be counted as pages.")
+
+	g, _ := dirHasName(s.Dirs, "guides")
+	assert.Equal("/guides", g.Path, "A directory should lead to its listing.")
+	assert.Equal("/guides/advanced", g.Children[0].Path, "A nested directory should lead to its listing.")
+
+	pg, ok := s.Page("/guides")
+	assert.True(ok, "A listing should be served from the directory.")
+	assert.Equal(&autodocs.Page{
+		Name:  "guides",
+		Title: "Guides",
+		Content: "<h1>Guides</h1>\n<ul>\n" +
+			"<li><a href=\"/guides/advanced\">Advanced</a></li>\n" +
+			"<li><a href=\"/guides/deploy\">Deploy</a></li>\n" +
+			"<li><a href=\"/guides/setup\">Setup</a> - Getting &lt;set&gt; up.</li>\n" +
+			"</ul>\n",
+	}, pg, "A listing should link to each page and directory, with descriptions.")
+
+	_, ok = dirHasName(g.Children, "draft")
+	assert.False(ok, "A hidden page should not be in the nav.")
+	_, ok = s.Page("/guides/draft")
+	assert.True(ok, "A hidden page should still be served.")
+
+	pg, ok = s.Page("/GUIDES/Advanced")
+	assert.True(ok, "A listing should be found regardless of case.")
+	assert.Contains(pg.Content, `<a href="/guides/advanced/tune">Tune</a>`)
+
+	pg, ok = s.Page("/")
+	assert.True(ok, "The root should be listed when it has no page.")
+	assert.Contains(pg.Content, `<a href="/api">The API</a>`, "A landing page should be linked to rather than listed.")
+	_, ok = s.Page("/api/missing")
+	assert.False(ok)
+
+	assert.Nil(util.WriteFile(fs, "guides/advanced/scale.md", []byte("# Scale"), 0644))
+	_, ok = s.Patch("", fs, []autodocs.Change{{Kind: autodocs.ChangeAdded, To: "guides/advanced/scale.md"}})
+	assert.True(ok)
+
+	full := NewStore()
+	full.Mount("", fs, autodocs.Index{})
+	assert.Equal(full.listings, s.listings, "Patching should give the same listings as a full mount.")
+	assert.Equal(full.Dirs, s.Dirs, "Patching should give the same tree as a full mount.")
+
+	assert.Nil(util.WriteFile(fs, "guides/deploy.md", []byte("---\nhidden: true\n---\n# Deploy"), 0644))
+	assert.Nil(util.WriteFile(fs, "guides/draft.md", []byte("# Draft"), 0644))
+	_, ok = s.Patch("", fs, []autodocs.Change{
+		{Kind: autodocs.ChangeModified, From: "guides/deploy.md", To: "guides/deploy.md"},
+		{Kind: autodocs.ChangeModified, From: "guides/draft.md", To: "guides/draft.md"},
+	})
+	assert.True(ok)
+
+	full = NewStore()
+	full.Mount("", fs, autodocs.Index{})
+	assert.Equal(full.listings, s.listings, "Hiding a page should give the same listings as a full mount.")
+	assert.Equal(full.Dirs, s.Dirs, "Hiding a page should give the same tree as a full mount.")
+	pg, _ = s.Page("/guides")
+	assert.NotContains(pg.Content, "Deploy", "A page made hidden should leave the listing.")
+	assert.Contains(pg.Content, `<a href="/guides/draft">Draft</a>`, "A page no longer hidden should be listed.")
+
+	assert.Nil(util.WriteFile(fs, "guides/index.md", []byte("# All guides"), 0644))
+	s.Mount("", fs, autodocs.Index{})
+	pg, _ = s.Page("/guides")
+	assert.Equal("All guides", pg.Title, "A landing page should replace the listing.")
+	assert.NotContains(s.listings, "/guides")
+}
@@ -0,0 +1,42 @@
+package docs
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Live holds the Store currently being served. Each Store is left
+// untouched once it has been swapped in, so readers are free to use
+// it without locking while a replacement is built.
+type Live struct {
+	// mu serialises updates, so that none are lost.
+	mu sync.Mutex
+
+	// v holds the current *Store.
+	v atomic.Value
+}
+
+// NewLive gives a Live serving an empty Store.
+func NewLive() *Live {
+	l := &Live{}
+	l.v.Store(NewStore())
+
+	return l
+}
+
+// Load gives the Store currently being served. It must not be
+// modified.
+func (l *Live) Load() *Store {
+	return l.v.Load().(*Store)
+}
+
+// Update will build a new Store from a copy of the current one, then
+// swap it in for readers once fn has returned.
+func (l *Live) Update(fn func(s *Store)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	s := l.Load().clone()
+	fn(s)
+	l.v.Store(s)
+}
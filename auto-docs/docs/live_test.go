@@ -0,0 +1,68 @@
+package docs
+
+import (
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+)
+
+func TestLiveUpdate(t *testing.T) {
+	assert := assert.New(t)
+	l := NewLive()
+	empty := l.Load()
+
+	l.Update(func(s *Store) {
+		s.Mount("ops", osfs.New(getTestMarkdownDir()), autodocs.Index{})
+	})
+	first := l.Load()
+	assert.Empty(empty.Pages, "A Store being served should not be changed.")
+	assert.Equal(3, len(first.Pages), "The updated Store should be served.")
+
+	when := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	l.Update(func(s *Store) {
+		s.Mount("platform", osfs.New(getTestMarkdownDir()+"first/"), autodocs.Index{})
+		s.Annotate("ops", map[string]autodocs.Commit{"first/one.md": {Author: "Ann", Date: when}})
+	})
+	second := l.Load()
+	assert.Equal(3, len(first.Pages), "Earlier pages should not be changed.")
+	assert.Equal(1, len(first.Dirs), "Earlier dirs should not be changed.")
+	assert.Empty(first.Pages["/ops/first/one"].Author, "Earlier pages should not be annotated.")
+	assert.Equal(5, len(second.Pages), "Updates should build on the current Store.")
+	assert.Equal(2, len(second.Dirs))
+	assert.Equal("Ann", second.Pages["/ops/first/one"].Author)
+}
+
+func TestLiveConcurrent(t *testing.T) {
+	l := NewLive()
+	fs := osfs.New(getTestMarkdownDir())
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				l.Update(func(s *Store) {
+					s.Mount("ops", fs, autodocs.Index{})
+					s.Annotate("ops", map[string]autodocs.Commit{"root.md": {Author: "Ann"}})
+				})
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				s := l.Load()
+				_, _ = json.Marshal(s)
+				_, _ = json.Marshal(s.Pages["/ops/root"])
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 3, len(l.Load().Pages), "Repeated updates should not duplicate pages.")
+}
@@ -0,0 +1,95 @@
+package docs
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// NavFile is the name of a file within a source that lists the order
+// of the pages and directories beside it.
+const NavFile = "_nav.yaml"
+
+// parseNav will read the names listed by a nav file, in the form they
+// take within the paths pages are served from.
+func parseNav(b []byte) ([]string, error) {
+	l := []string{}
+	if err := yaml.Unmarshal(b, &l); err != nil {
+		return nil, fmt.Errorf("unable to parse nav file: %s", err)
+	}
+
+	for i, x := range l {
+		l[i] = strings.ToLower(slug(strings.TrimSuffix(strings.Trim(x, "/"), ".md")))
+	}
+
+	return l, nil
+}
+
+// sortDirs will order each Dir, and all of their children, within the
+// directory served from the path.
+func (s *Store) sortDirs(d []*Dir, p string) {
+	s.sortLevel(d, p)
+	for _, x := range d {
+		s.sortDirs(x.Children, path.Join(p, x.Name))
+	}
+}
+
+// sortLevel will order each Dir within the directory served from the
+// path, leaving their children as they are. Those listed by a nav file
+// go first, then those with a weight from lowest to highest, then the
+// rest alphabetically.
+func (s *Store) sortLevel(d []*Dir, p string) {
+	nav := s.nav(p)
+	rank := func(x *Dir) int {
+		n := strings.ToLower(x.Name)
+		for i, y := range nav {
+			if y == n {
+				return i
+			}
+		}
+
+		return len(nav)
+	}
+
+	sort.SliceStable(d, func(i, j int) bool {
+		a, b := d[i], d[j]
+		if x, y := rank(a), rank(b); x != y {
+			return x < y
+		}
+		if x, y := s.weight(a), s.weight(b); x != y {
+			// no weight at all goes after any weight
+			return y == 0 || (x != 0 && x < y)
+		}
+
+		if x, y := strings.ToLower(a.Text), strings.ToLower(b.Text); x != y {
+			return x < y
+		}
+
+		return a.Name < b.Name
+	})
+}
+
+// nav gives the order listed by the nav file of the directory served
+// from the path, taking the first mount to have one.
+func (s *Store) nav(p string) []string {
+	for _, m := range s.order {
+		if l, ok := s.navs[m][p]; ok {
+			return l
+		}
+	}
+
+	return nil
+}
+
+// weight gives the weight of the page a Dir leads to, or 0 when it
+// has none.
+func (s *Store) weight(d *Dir) int {
+	if pg, ok := s.Pages[d.Path]; ok && d.Path != "" {
+		return pg.Weight
+	}
+
+	return 0
+}
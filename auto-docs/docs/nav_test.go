@@ -0,0 +1,102 @@
+package docs
+
+import (
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+// names gives the name of each Dir, in order.
+func names(d []*Dir) []string {
+	r := []string{}
+	for _, x := range d {
+		r = append(r, x.Name)
+	}
+
+	return r
+}
+
+func TestSortDirs(t *testing.T) {
+	assert := assert.New(t)
+	fs := memfs.New()
+	for n, c := range map[string]string{
+		"deployment.md":            "# deployment",
+		"faq.md":                   "# faq",
+		"getting-started.md":       "---\nweight: 1\n---\n# getting started",
+		"Upgrading.md":             "---\nweight: 2\n---\n# upgrading",
+		"api/auth.md":              "# auth",
+		"guides/_nav.yaml":         "- setup.md\n- advanced/\n- Rollback",
+		"guides/advanced/scale.md": "# scale",
+		"guides/deploy.md":         "---\nweight: 1\n---\n# deploy",
+		"guides/rollback.md":       "# rollback",
+		"guides/setup.md":          "---\nweight: 5\n---\n# setup",
+		"guides/zebra.md":          "# zebra",
+	} {
+		assert.Nil(util.WriteFile(fs, n, []byte(c), 0644))
+	}
+
+	s := NewStore()
+	s.Mount("", fs, autodocs.Index{})
+
+	assert.Equal(10, len(s.Pages), "The nav file should not be served.")
+	assert.Equal(
+		[]string{"getting-started", "Upgrading", "api", "deployment", "faq", "guides"},
+		names(s.Dirs),
+		"Weighted pages should go first, then the rest alphabetically.",
+	)
+
+	g, _ := dirHasName(s.Dirs, "guides")
+	assert.Equal(
+		[]string{"setup", "advanced", "rollback", "deploy", "zebra"},
+		names(g.Children),
+		"The nav file should order both pages and directories first.",
+	)
+
+	assert.Nil(util.WriteFile(fs, "faq.md", []byte("---\nweight: 3\n---\n# faq"), 0644))
+	n, ok := s.Patch("", fs, []autodocs.Change{{Kind: autodocs.ChangeModified, From: "faq.md", To: "faq.md"}})
+	assert.True(ok)
+	assert.Equal(10, n)
+
+	full := NewStore()
+	full.Mount("", fs, autodocs.Index{})
+	assert.Equal(full.Dirs, s.Dirs, "A changed weight should give the same tree as a full mount.")
+	assert.Equal("faq", s.Dirs[2].Name, "A changed weight should move the page.")
+
+	assert.Nil(util.WriteFile(fs, "guides/_nav.yaml", []byte("setup: 1"), 0644))
+	s.Mount("", fs, autodocs.Index{})
+	g, _ = dirHasName(s.Dirs, "guides")
+	assert.Equal(
+		[]string{"deploy", "setup", "advanced", "rollback", "zebra"},
+		names(g.Children),
+		"An invalid nav file should be ignored.",
+	)
+}
+
+type parseNavStruct struct {
+	In     string
+	Exp    []string
+	ExpErr bool
+	M      string
+}
+
+func TestParseNav(t *testing.T) {
+	assert := assert.New(t)
+	x := []parseNavStruct{
+		{In: "- a\n- b", Exp: []string{"a", "b"}, M: "Names should be listed in order."},
+		{In: "- Setup.md\n- guides/", Exp: []string{"setup", "guides"}, M: "Names should match the paths served."},
+		{In: "", Exp: []string{}, M: "An empty nav file should list nothing."},
+		{In: "a: b", ExpErr: true, M: "A nav file should only be a list."},
+	}
+
+	for _, a := range x {
+		l, err := parseNav([]byte(a.In))
+
+		assert.Equal(a.ExpErr, err != nil, a.M)
+		if !a.ExpErr {
+			assert.Equal(a.Exp, l, a.M)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package docs
+
+import (
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// Patch will update the pages mounted under the prefix from the files
+// that changed within the source, re-rendering only those pages and
+// rebuilding only the parts of the tree they are within. It gives
+// the number of pages under the mount, or false when the source
+// must be mounted in full instead, as it has not been mounted before,
+// an ignore or nav file has changed, a landing file was added,
+// removed or renamed, or a file shares its path with another.
+func (s *Store) Patch(m string, fs billy.Filesystem, changes []autodocs.Change) (int, bool) {
+	f, ok := s.filters[m]
+	if _, mounted := s.mounts[m]; !ok || !mounted {
+		return 0, false
+	}
+	for _, x := range changes {
+		for _, n := range []string{path.Base(x.From), path.Base(x.To)} {
+			if n == IgnoreFile || n == NavFile {
+				return 0, false
+			}
+		}
+		if x.Kind != autodocs.ChangeModified && (landing(x.From) >= 0 || landing(x.To) >= 0) {
+			return 0, false
+		}
+		if f.clashes[x.From] || f.clashes[x.To] {
+			return 0, false
+		}
+	}
+
+	files := map[string]string{}
+	for _, x := range s.mounts[m] {
+		files[x.file] = x.key
+	}
+	if clashes(m, f, files, changes) {
+		return 0, false
+	}
+	touched := map[string]bool{}
+
+	// remove everything first, so that a path may be reused
+	from := make([]string, len(changes))
+	for i, x := range changes {
+		if k, ok := files[x.From]; ok && x.Kind != autodocs.ChangeAdded {
+			delete(files, x.From)
+			delete(s.Pages, k)
+			touched[k], from[i] = true, k
+		}
+	}
+
+	for i, x := range changes {
+		if x.Kind == autodocs.ChangeRemoved {
+			continue
+		}
+
+		k, ok := f.Key(m, x.To)
+		if !ok {
+			continue
+		}
+
+		pg, err := buildPage(k, fs, x.To)
+		if err != nil {
+			log.Println("unable to build page:", err)
+			continue
+		}
+
+		s.Pages[k] = pg
+		files[x.To] = k
+		touched[k] = true
+		if x.Kind == autodocs.ChangeRenamed && from[i] != "" && from[i] != k {
+			s.redirect(from[i], k)
+		}
+	}
+
+	l := make([]entry, 0, len(files))
+	for x, k := range files {
+		l = append(l, entry{file: x, key: k})
+	}
+	sort.Slice(l, func(i, j int) bool { return walkOrder(l[i].file, l[j].file) })
+
+	s.mounts[m] = l
+	s.tidy()
+	s.rebuildFor(touched)
+
+	return len(l), true
+}
+
+// clashes checks if any file added or renamed by the changes would be
+// served from the same path as another file that is still mounted, or
+// as another of the changes.
+func clashes(m string, f *Filter, files map[string]string, changes []autodocs.Change) bool {
+	gone := map[string]bool{}
+	for _, x := range changes {
+		if x.Kind != autodocs.ChangeAdded {
+			gone[x.From] = true
+		}
+	}
+
+	held := map[string]bool{}
+	for x, k := range files {
+		if !gone[x] {
+			held[k] = true
+		}
+	}
+
+	for _, x := range changes {
+		if x.Kind == autodocs.ChangeRemoved {
+			continue
+		}
+		if k, ok := f.Key(m, x.To); ok {
+			if held[k] {
+				return true
+			}
+			held[k] = true
+		}
+	}
+
+	return false
+}
+
+// rebuildFor will rebuild only the parts of the structure of pages
+// that hold the paths, leaving the rest as it was. The result is the
+// same as building from scratch.
+func (s *Store) rebuildFor(keys map[string]bool) {
+	affected := map[string]bool{}
+	for k := range keys {
+		affected[tokenise(k)[0]] = true
+	}
+
+	fresh := []*Dir{}
+	for _, m := range s.order {
+		for _, x := range s.mounts[m] {
+			if affected[tokenise(x.key)[0]] && s.listed(x.key) {
+				fresh = addToDir(fresh, x.key, x.key)
+			}
+		}
+	}
+	s.title(fresh)
+	s.sortDirs(fresh, "/")
+
+	d := make([]*Dir, 0, len(s.Dirs)+len(fresh))
+	for _, x := range s.Dirs {
+		if !affected[x.Name] {
+			d = append(d, x)
+		}
+	}
+	s.Dirs = append(d, fresh...)
+	s.sortLevel(s.Dirs, "/")
+
+	l := map[string]*autodocs.Page{}
+	for k, v := range s.listings {
+		if k != "/" && !affected[tokenise(k)[0]] {
+			l[k] = v
+		}
+	}
+	s.listDirs(fresh, "/", l)
+	s.listings = l
+	s.listRoot(s.listings)
+	s.fold()
+}
+
+// walkOrder checks if a slash separated path is found before another
+// when walking a source, where each directory is read in order.
+func walkOrder(a, b string) bool {
+	x, y := strings.Split(a, "/"), strings.Split(b, "/")
+	for i := 0; i < len(x) && i < len(y); i++ {
+		if x[i] != y[i] {
+			return x[i] < y[i]
+		}
+	}
+
+	return len(x) < len(y)
+}
@@ -0,0 +1,89 @@
+package docs
+
+import (
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+func TestPatch(t *testing.T) {
+	assert := assert.New(t)
+	fs := memfs.New()
+	for n, c := range map[string]string{
+		"readme.md":          "# readme",
+		"old.md":             "# old",
+		"setup.md":           "# setup",
+		"api/auth.md":        "# auth",
+		"guides/deploy.md":   "# deploy",
+		"guides/rollback.md": "# rollback",
+		"zoo/animals.md":     "# animals",
+	} {
+		assert.Nil(util.WriteFile(fs, n, []byte(c), 0644))
+	}
+
+	s := NewStore()
+	_, ok := s.Patch("", fs, nil)
+	assert.False(ok, "A source not yet mounted should be mounted in full.")
+
+	s.Mount("", fs, autodocs.Index{})
+	s.Mount("/other", osfs.New(getTestMarkdownDir()+"first/"), autodocs.Index{})
+	auth, zoo := s.Pages["/api/auth"], s.Dirs[len(s.Dirs)-1]
+
+	assert.Nil(fs.Remove("old.md"))
+	assert.Nil(util.WriteFile(fs, "readme.md", []byte("# read me"), 0644))
+	assert.Nil(fs.Rename("setup.md", "guides/setup.md"))
+	assert.Nil(util.WriteFile(fs, "guides/setup.md", []byte("# set up"), 0644))
+	assert.Nil(util.WriteFile(fs, "a.md", []byte("# a"), 0644))
+	assert.Nil(util.WriteFile(fs, "notes.txt", []byte("notes"), 0644))
+
+	n, ok := s.Patch("", fs, []autodocs.Change{
+		{Kind: autodocs.ChangeAdded, To: "a.md"},
+		{Kind: autodocs.ChangeRenamed, From: "setup.md", To: "guides/setup.md"},
+		{Kind: autodocs.ChangeAdded, To: "notes.txt"},
+		{Kind: autodocs.ChangeRemoved, From: "old.md"},
+		{Kind: autodocs.ChangeModified, From: "readme.md", To: "readme.md"},
+	})
+	assert.True(ok)
+	assert.Equal(7, n, "Each page under the mount should be counted.")
+
+	full := NewStore()
+	full.Mount("", fs, autodocs.Index{})
+	full.Mount("/other", osfs.New(getTestMarkdownDir()+"first/"), autodocs.Index{})
+
+	assert.Equal(full.Pages, s.Pages, "Patching should give the same pages as a full mount.")
+	assert.Equal(full.Dirs, s.Dirs, "Patching should give the same tree as a full mount.")
+	assert.Equal(map[string]string{"/setup": "/guides/setup"}, s.Redirects, "Renames should be redirected.")
+	assert.True(auth == s.Pages["/api/auth"], "Unchanged pages should not be rendered again.")
+	assert.True(zoo == s.Dirs[len(s.Dirs)-1], "Unaffected dirs should not be built again.")
+
+	_, ok = s.Patch("", fs, []autodocs.Change{{Kind: autodocs.ChangeAdded, To: "guides/" + IgnoreFile}})
+	assert.False(ok, "A changed ignore file should need a full mount.")
+
+	_, ok = s.Patch("", fs, []autodocs.Change{{Kind: autodocs.ChangeRemoved, From: NavFile}})
+	assert.False(ok, "A changed nav file should need a full mount.")
+}
+
+type walkOrderStruct struct {
+	A   string
+	B   string
+	Exp bool
+	M   string
+}
+
+func TestWalkOrder(t *testing.T) {
+	assert := assert.New(t)
+	x := []walkOrderStruct{
+		{A: "a.md", B: "b.md", Exp: true, M: "Files should be in order."},
+		{A: "a/z.md", B: "a.md", Exp: true, M: "A directory should be walked before a longer sibling."},
+		{A: "a-b.md", B: "a/x.md", Exp: false, M: "Each part of the path should be compared alone."},
+		{A: "a/b.md", B: "a/c/d.md", Exp: true, M: "Deeper paths should be compared by each directory."},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, walkOrder(a.A, a.B), a.M)
+	}
+}
@@ -12,34 +12,34 @@ import (
 	"io/ioutil"
 	"log"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	autodocs "github.com/cloudcloud/auto-docs"
 	"gitlab.com/golang-commonmark/markdown"
+	"gopkg.in/src-d/go-billy.v4"
 )
 
-var (
-	// S is a singleton instance of the current docs storage.
-	S = &Store{}
-)
-
-func init() {
-	S.Dirs = []*Dir{}
-	S.Pages = make(map[string]*autodocs.Page, 0)
-}
-
 // Dir gives a holder of further nodes.
 type Dir struct {
 	Children []*Dir `json:"children"`
 	Icon     string `json:"icon"`
 	IconAlt  string `json:"icon-alt"`
 	Model    bool   `json:"model"`
+	Name     string `json:"name"`
 	Path     string `json:"path"`
 	Text     string `json:"text"`
 }
 
-// Store captures the doc file references and content.
+// entry is a file within a source, and the path it is served from.
+type entry struct {
+	file, key string
+}
+
+// Store captures the doc file references and content. Once being
+// served by way of Live, a Store is only ever read.
 type Store struct {
 	// Dirs tracks the structure of pages under their paths.
 	Dirs []*Dir `json:"pages"`
@@ -47,66 +47,460 @@ type Store struct {
 	// Pages captures the content for a full path page.
 	Pages map[string]*autodocs.Page `json:"-"`
 
+	// Redirects captures the path each page that has been renamed
+	// or moved is now served from, by its previous path.
+	Redirects map[string]string `json:"redirects,omitempty"`
+
+	// files lists the path of each file found within the source
+	// currently being added.
+	files []string
+
+	// listings captures the page generated for each directory that
+	// has no page of its own, by its path.
+	listings map[string]*autodocs.Page
+
+	// folded gives the path of each page by its lower case form, so
+	// that pages may be found regardless of case.
+	folded map[string]string
+
+	// filters keeps the Filter used for each mount.
+	filters map[string]*Filter
+
+	// fs is the filesystem of the source currently being added.
+	fs billy.Filesystem
+
+	// mount is the prefix pages are currently being added under.
+	mount string
+
+	// mounts keeps each page added under each mount, in the order
+	// they were found.
+	mounts map[string][]entry
+
+	// navs keeps the order listed by each nav file under each mount,
+	// by the path of the directory it is within.
+	navs map[string]map[string][]string
+
+	// order lists each mount in the order it was first added.
+	order []string
+
 	// path is the base that this store is defined for.
 	path string
 }
 
-// UpdateFromPath will accept a base path location and walk the
-// directory structure to find appropriate files to be pulled in
-// to memory for serving.
-func (s *Store) UpdateFromPath(p string) {
-	s.path = p
-	filepath.Walk(p, s.walker)
+// Mount will walk the directory structure of a filesystem, adding
+// appropriate files to be served under the provided prefix. This
+// allows for multiple sources to be merged into the one tree. Only
+// the files allowed by the index config and any ignore files are
+// added, giving the number of pages added. Any pages previously
+// mounted under the prefix are replaced, with those that were moved
+// unchanged recorded as redirects.
+func (s *Store) Mount(m string, fs billy.Filesystem, c autodocs.Index) int {
+	if s.filters == nil {
+		s.filters = map[string]*Filter{}
+	}
+	if s.mounts == nil {
+		s.mounts = map[string][]entry{}
+	}
+	if s.navs == nil {
+		s.navs = map[string]map[string][]string{}
+	}
+	s.mount = m
+	s.fs = fs
+	s.path = "/"
+	s.files = []string{}
+
+	// walk towards the root first, then apply any ignore files
+	s.filters[m] = NewFilter(c, nil, nil)
+	walk(fs, s.path, s.walker)
+	f := NewFilter(c, s.files, func(x string) ([]byte, error) {
+		return readFile(fs, x)
+	})
+	s.filters[m] = f
+	s.navs[m] = s.readNavs(m, f)
+
+	before := s.unmount(m)
+	l := []entry{}
+	for _, x := range s.files {
+		k, ok := f.Key(m, x)
+		if !ok {
+			continue
+		}
+
+		pg, err := buildPage(k, fs, x)
+		if err == nil {
+			s.Pages[k] = pg
+			l = append(l, entry{file: x, key: k})
+		}
+	}
+	s.files, s.fs = nil, nil
+
+	if _, ok := s.mounts[m]; !ok {
+		s.order = append(s.order, m)
+	}
+	s.mounts[m] = l
+	s.moved(before, l)
+	s.rebuild()
+
+	return len(l)
+}
+
+// Redirect will record the pages mounted under the prefix that are
+// renamed by the next Mount, from file paths relative to the source.
+// This allows for pages that were changed as they were moved, which
+// Mount alone is unable to find.
+func (s *Store) Redirect(m string, renames []autodocs.Change) {
+	f, ok := s.filters[m]
+	if !ok {
+		return
+	}
+
+	for _, x := range renames {
+		if x.Kind != autodocs.ChangeRenamed {
+			continue
+		}
+
+		from, aok := f.Key(m, x.From)
+		to, bok := f.Key(m, x.To)
+		if aok && bok && from != to {
+			s.redirect(from, to)
+		}
+	}
+}
+
+// unmount will remove every page added under the prefix, giving
+// each of them by path.
+func (s *Store) unmount(m string) map[string]*autodocs.Page {
+	r := map[string]*autodocs.Page{}
+	for _, x := range s.mounts[m] {
+		if p, ok := s.Pages[x.key]; ok {
+			r[x.key] = p
+			delete(s.Pages, x.key)
+		}
+	}
+
+	return r
+}
+
+// moved will record a redirect for each page that is no longer
+// served, to a page newly added with the same content.
+func (s *Store) moved(before map[string]*autodocs.Page, l []entry) {
+	added := []string{}
+	for _, x := range l {
+		if _, ok := before[x.key]; !ok {
+			added = append(added, x.key)
+		}
+	}
+
+	gone := []string{}
+	for k := range before {
+		if _, ok := s.Pages[k]; !ok {
+			gone = append(gone, k)
+		}
+	}
+	sort.Strings(gone)
+
+	for _, k := range gone {
+		for i, n := range added {
+			if s.Pages[n].Content == before[k].Content {
+				s.redirect(k, n)
+				added = append(added[:i], added[i+1:]...)
+				break
+			}
+		}
+	}
+	s.tidy()
+}
+
+// redirect will record a page moving from one path to another,
+// following on from any earlier move to the previous path.
+func (s *Store) redirect(from, to string) {
+	if s.Redirects == nil {
+		s.Redirects = map[string]string{}
+	}
+
+	for k, v := range s.Redirects {
+		if v == from {
+			s.Redirects[k] = to
+		}
+	}
+	s.Redirects[from] = to
+}
+
+// tidy will remove any redirect from a path that is served once
+// again, or to a path that is no longer served.
+func (s *Store) tidy() {
+	for k, v := range s.Redirects {
+		_, served := s.Pages[k]
+		if _, ok := s.Pages[v]; served || !ok || k == v {
+			delete(s.Redirects, k)
+		}
+	}
+}
+
+// readNavs will load each nav file found within the source that is
+// being added, by the path of the directory it is within.
+func (s *Store) readNavs(m string, f *Filter) map[string][]string {
+	r := map[string][]string{}
+	for _, x := range s.files {
+		if path.Base(x) != NavFile {
+			continue
+		}
+
+		k, ok := f.Dir(m, path.Dir(x))
+		if !ok {
+			continue
+		}
+
+		b, err := readFile(s.fs, x)
+		if err != nil {
+			log.Println("unable to read nav file:", x)
+			continue
+		}
+
+		l, err := parseNav(b)
+		if err != nil {
+			log.Println(err, "for file:", x)
+			continue
+		}
+		r[k] = l
+	}
+
+	return r
+}
+
+// rebuild will build the structure of pages from scratch, then put
+// each level in order.
+func (s *Store) rebuild() {
+	s.Dirs = []*Dir{}
+	for _, m := range s.order {
+		for _, x := range s.mounts[m] {
+			if s.listed(x.key) {
+				s.Dirs = addToDir(s.Dirs, x.key, x.key)
+			}
+		}
+	}
+	s.title(s.Dirs)
+	s.sortDirs(s.Dirs, "/")
+
+	s.listings = map[string]*autodocs.Page{}
+	s.listDirs(s.Dirs, "/", s.listings)
+	s.listRoot(s.listings)
+	s.fold()
+}
+
+// listed checks if the page served from the path is shown within the
+// structure of pages and listings, which a hidden page is not.
+func (s *Store) listed(p string) bool {
+	pg, ok := s.Pages[p]
+	return !ok || !pg.Hidden
+}
+
+// title will set the text of each Dir leading to a page, and all of
+// their children, to the title of the page.
+func (s *Store) title(d []*Dir) {
+	for _, x := range d {
+		if pg, ok := s.Pages[x.Path]; ok && x.Path != "" && pg.Title != "" {
+			x.Text = pg.Title
+		}
+		s.title(x.Children)
+	}
+}
+
+// fold will index the path of each page, and each listing, by its
+// lower case form. The first path found in order is kept, should
+// several differ by case.
+func (s *Store) fold() {
+	s.folded = make(map[string]string, len(s.Pages)+len(s.listings))
+	for _, m := range s.order {
+		for _, x := range s.mounts[m] {
+			if _, ok := s.folded[strings.ToLower(x.key)]; !ok {
+				s.folded[strings.ToLower(x.key)] = x.key
+			}
+		}
+	}
+
+	l := make([]string, 0, len(s.listings))
+	for k := range s.listings {
+		l = append(l, k)
+	}
+	sort.Strings(l)
+	for _, k := range l {
+		if _, ok := s.folded[strings.ToLower(k)]; !ok {
+			s.folded[strings.ToLower(k)] = k
+		}
+	}
+}
+
+// Page gives the page served from the path, ignoring case when there
+// is no exact match. Directories without a page of their own are
+// served a listing of what is within them.
+func (s *Store) Page(p string) (*autodocs.Page, bool) {
+	for _, k := range []string{p, s.folded[strings.ToLower(p)]} {
+		if pg, ok := s.Pages[k]; ok {
+			return pg, true
+		}
+		if pg, ok := s.listings[k]; ok {
+			return pg, true
+		}
+	}
+
+	return nil, false
+}
+
+// Moved gives the path that a page previously served from the path
+// has moved to, ignoring case when there is no exact match.
+func (s *Store) Moved(p string) (string, bool) {
+	if to, ok := s.Redirects[p]; ok {
+		return to, true
+	}
+
+	for k, v := range s.Redirects {
+		if strings.EqualFold(k, p) {
+			return v, true
+		}
+	}
+
+	return "", false
+}
+
+// Annotate will record the last change to each page mounted under
+// the prefix, from the latest commit for each file path relative
+// to the source. Pages are replaced rather than changed, as they
+// may be shared with a Store being served.
+func (s *Store) Annotate(m string, info map[string]autodocs.Commit) {
+	for f, c := range info {
+		k := Key(m, filepath.FromSlash(f))
+		if x, ok := s.filters[m]; ok {
+			k, _ = x.Key(m, f)
+		}
+
+		p, ok := s.Pages[k]
+		if !ok {
+			continue
+		}
+
+		d, x := c.Date, *p
+		x.Author = c.Author
+		x.Modified = &d
+		s.Pages[k] = &x
+	}
 }
 
-// walker is the handler method for directory traversal.
+// clone gives a copy of the Store that can be changed without
+// affecting this one. Pages themselves are shared.
+func (s *Store) clone() *Store {
+	c := &Store{
+		Dirs:      copyDirs(s.Dirs),
+		Pages:     make(map[string]*autodocs.Page, len(s.Pages)),
+		Redirects: make(map[string]string, len(s.Redirects)),
+		folded:    s.folded,
+		listings:  s.listings,
+		filters:   map[string]*Filter{},
+		mounts:    map[string][]entry{},
+		navs:      map[string]map[string][]string{},
+		order:     append([]string{}, s.order...),
+	}
+	for k, v := range s.Pages {
+		c.Pages[k] = v
+	}
+	for k, v := range s.Redirects {
+		c.Redirects[k] = v
+	}
+	for k, v := range s.filters {
+		c.filters[k] = v
+	}
+	for k, v := range s.mounts {
+		c.mounts[k] = v
+	}
+	for k, v := range s.navs {
+		c.navs[k] = v
+	}
+
+	return c
+}
+
+// copyDirs will copy each Dir and all of their children.
+func copyDirs(d []*Dir) []*Dir {
+	if d == nil {
+		return nil
+	}
+
+	r := make([]*Dir, len(d))
+	for i, x := range d {
+		y := *x
+		y.Children = copyDirs(x.Children)
+		r[i] = &y
+	}
+
+	return r
+}
+
+// walker is the handler method for directory traversal, finding
+// the markdown, ignore and nav files within the source.
 func (s *Store) walker(path string, i os.FileInfo, err error) error {
 	if err != nil {
 		log.Println("unable to read path:", path)
 		return err
 	}
 
-	// skip our own processing of folders and irrelevant files
-	if i.IsDir() || !strings.HasSuffix(i.Name(), ".md") {
+	r, err := filepath.Rel(s.path, path)
+	if err != nil {
+		log.Println("unable to resolve path:", path)
 		return nil
 	}
+	r = filepath.ToSlash(r)
 
-	x := strings.TrimPrefix(strings.TrimSuffix(strings.ToLower(path), ".md"), s.path)
-	p, err := buildPage(x, path)
-	if err == nil {
-		s.Pages[x] = p
-		s.Dirs = addToDir(s.Dirs, x, x)
+	// skip directories away from the root or excluded, and
+	// irrelevant files
+	if i.IsDir() {
+		if f, ok := s.filters[s.mount]; i.Name() == ".git" || (ok && (!f.toward(r) || f.skips(r))) {
+			return filepath.SkipDir
+		}
+		return nil
+	}
+	if !strings.HasSuffix(i.Name(), ".md") && i.Name() != IgnoreFile && i.Name() != NavFile {
+		return nil
 	}
 
+	s.files = append(s.files, r)
 	return nil
 }
 
 // addToDir will push a path into the slice of Dir
 // entries, working through entries in the slice to
-// prevent duplication of entries.
+// prevent duplication of entries. A path that is also
+// a directory leads to both its page and children.
 func addToDir(d []*Dir, p, o string) []*Dir {
 	b := tokenise(p)
-	dir, yes := dirHasText(d, strings.Title(b[0]))
+	if b[0] == "" {
+		// the root is not a node of its own
+		return d
+	}
+	dir, yes := dirHasName(d, b[0])
 
 	if !yes {
 		// create a dir and then descend
 		dir = &Dir{
-			Text: strings.Title(b[0]),
+			Name: b[0],
+			Text: humanize(b[0]),
 		}
+		d = append(d, dir)
+	}
 
-		if len(b) > 1 {
-			// have children
-			dir.Icon = "keyboard_arrow_up"
-			dir.IconAlt = "keyboard_arrow_down"
+	if len(b) > 1 {
+		// have children
+		dir.Icon = "keyboard_arrow_up"
+		dir.IconAlt = "keyboard_arrow_down"
+		if dir.Children == nil {
 			dir.Children = []*Dir{}
-		} else {
-			// no children
+		}
+	} else {
+		// the page itself
+		dir.Path = o
+		if dir.Children == nil {
 			dir.Icon = "note"
 			dir.IconAlt = "note"
-			dir.Path = o
 		}
-
-		d = append(d, dir)
 	}
 
 	// dir is the node to descend into now
@@ -122,26 +516,117 @@ func addToDir(d []*Dir, p, o string) []*Dir {
 	return d
 }
 
-// buildPage
-func buildPage(p, d string) (*autodocs.Page, error) {
+// Key gives the path a markdown file is served from, based on
+// its location relative to the root of a source and the prefix
+// the source is mounted under. The case of the file is kept, with
+// any spaces as hyphens.
+func Key(m, r string) string {
+	return filepath.Join(
+		string(os.PathSeparator),
+		m,
+		slug(strings.TrimSuffix(r, ".md")),
+	)
+}
+
+// NewPage will render the markdown content for the page served
+// from the path. Any front matter is parsed in to the page, rather
+// than rendered, and is left in place should it be invalid. Without
+// a title in the front matter, the first top level heading is used,
+// then the name of the page.
+func NewPage(p string, f []byte) *autodocs.Page {
 	b := tokenise(p)
 	m := markdown.New(markdown.XHTMLOutput(true))
-	f, err := ioutil.ReadFile(d)
+	pg := &autodocs.Page{Name: b[len(b)-1]}
+
+	if y, body, ok := splitFrontMatter(f); ok {
+		if err := parseFrontMatter(pg, y); err != nil {
+			log.Println(err, "for page:", p)
+		} else {
+			f = body
+		}
+	}
+
+	t := m.Parse(f)
+	if pg.Title == "" {
+		pg.Title = heading(t)
+	}
+	if pg.Title == "" {
+		pg.Title = humanize(pg.Name)
+	}
+
+	pg.Content = m.RenderTokensToString(t)
+	return pg
+}
+
+// buildPage will load the markdown file from the filesystem and
+// render it.
+func buildPage(p string, fs billy.Filesystem, d string) (*autodocs.Page, error) {
+	f, err := readFile(fs, d)
 	if err != nil {
 		return nil, fmt.Errorf("unable to load markdown file: %s", err)
 	}
 
-	return &autodocs.Page{
-		Name:    b[len(b)-1],
-		Content: m.RenderToString(f),
-	}, nil
+	return NewPage(p, f), nil
+}
+
+// readFile gives the content of a file within the filesystem.
+func readFile(fs billy.Filesystem, p string) ([]byte, error) {
+	f, err := fs.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ioutil.ReadAll(f)
+}
+
+// walk will traverse the filesystem from the path, calling fn for
+// each file and directory in the same way as filepath.Walk.
+func walk(fs billy.Filesystem, p string, fn filepath.WalkFunc) error {
+	i, err := fs.Lstat(p)
+	if os.IsNotExist(err) && p == "/" {
+		// an empty memory filesystem has no root
+		return nil
+	} else if err != nil {
+		return fn(p, nil, err)
+	}
+
+	err = walkDir(fs, p, i, fn)
+	if err == filepath.SkipDir {
+		return nil
+	}
+
+	return err
+}
+
+// walkDir will call fn for the path, then for everything beneath it
+// in lexical order when it is a directory.
+func walkDir(fs billy.Filesystem, p string, i os.FileInfo, fn filepath.WalkFunc) error {
+	if !i.IsDir() {
+		return fn(p, i, nil)
+	}
+
+	l, err := fs.ReadDir(p)
+	if err := fn(p, i, err); err != nil || l == nil {
+		return err
+	}
+	sort.Slice(l, func(a, b int) bool { return l[a].Name() < l[b].Name() })
+
+	for _, x := range l {
+		err := walkDir(fs, path.Join(p, x.Name()), x, fn)
+		if err != nil && (!x.IsDir() || err != filepath.SkipDir) {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// dirHasText will look for an existing Dir in the slice
-// that has the requested Text value.
-func dirHasText(d []*Dir, t string) (*Dir, bool) {
+// dirHasName will look for an existing Dir in the slice
+// that has the requested Name value.
+func dirHasName(d []*Dir, n string) (*Dir, bool) {
 	for _, x := range d {
-		if t == x.Text {
+		if n == x.Name {
 			return x, true
 		}
 	}
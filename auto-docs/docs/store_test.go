@@ -5,11 +5,15 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	autodocs "github.com/cloudcloud/auto-docs"
 	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+	"gopkg.in/src-d/go-billy.v4/util"
 )
 
 type pathStruct struct {
@@ -22,7 +26,7 @@ type pathStruct struct {
 	N          string
 }
 
-func TestUpdateFromPath(t *testing.T) {
+func TestMountPath(t *testing.T) {
 	assert := assert.New(t)
 	x := []pathStruct{
 		{
@@ -52,13 +56,76 @@ func TestUpdateFromPath(t *testing.T) {
 		}
 		log.SetOutput(a.Buffer)
 
-		s.UpdateFromPath(a.InpDir)
+		s.Mount("", osfs.New(a.InpDir), autodocs.Index{})
 		assert.Equal(a.CountPages, len(s.Pages), a.M)
 		assert.Equal(a.CountDirs, len(s.Dirs), a.N)
 		//assert.Equal(a.ExpLogs, a.Buffer.String())
 	}
 }
 
+func TestMount(t *testing.T) {
+	assert := assert.New(t)
+	s := &Store{
+		Dirs:  []*Dir{},
+		Pages: map[string]*autodocs.Page{},
+	}
+
+	assert.Equal(3, s.Mount("payments", osfs.New(getTestMarkdownDir()), autodocs.Index{}), "Each page added should be counted.")
+	assert.Equal(2, s.Mount("/platform/", osfs.New(getTestMarkdownDir()+"first/"), autodocs.Index{}), "Each page added should be counted.")
+
+	assert.Equal(5, len(s.Pages), "Both sources should be loaded.")
+	assert.Contains(s.Pages, "/payments/root", "Pages should be under their mount.")
+	assert.Contains(s.Pages, "/payments/first/one", "Nested pages should be under their mount.")
+	assert.Contains(s.Pages, "/platform/two", "Surrounding slashes should be ignored.")
+	assert.Equal(2, len(s.Dirs), "Each mount should be a top level dir.")
+	assert.Equal("Payments", s.Dirs[0].Text, "Mount should be the dir text.")
+}
+
+func TestMountMemory(t *testing.T) {
+	assert := assert.New(t)
+	fs := memfs.New()
+	for n, c := range map[string]string{
+		"readme.md":              "# readme",
+		"guides/setup.md":        "# setup",
+		"guides/.autodocsignore": "draft.md",
+		"guides/draft.md":        "# draft",
+		".git/notes.md":          "# not docs",
+		"main.go":                "package main",
+	} {
+		assert.Nil(util.WriteFile(fs, n, []byte(c), 0644))
+	}
+
+	s := &Store{
+		Dirs:  []*Dir{},
+		Pages: map[string]*autodocs.Page{},
+	}
+	s.Mount("", fs, autodocs.Index{})
+
+	assert.Equal(2, len(s.Pages), "Only served markdown should be loaded from memory.")
+	assert.Equal("<h1>setup</h1>\n", s.Pages["/guides/setup"].Content, "Content should be read from memory.")
+	assert.Equal("Guides", s.Dirs[0].Text, "Dirs should be built in order.")
+}
+
+func TestAnnotate(t *testing.T) {
+	assert := assert.New(t)
+	s := &Store{
+		Dirs:  []*Dir{},
+		Pages: map[string]*autodocs.Page{},
+	}
+	s.Mount("ops", osfs.New(getTestMarkdownDir()), autodocs.Index{})
+
+	when := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	s.Annotate("ops", map[string]autodocs.Commit{
+		"first/one.md": {Author: "Ann", Date: when},
+		"missing.md":   {Author: "Bo", Date: when},
+	})
+
+	assert.Equal("Ann", s.Pages["/ops/first/one"].Author, "Author should be set on the page.")
+	assert.Equal(&when, s.Pages["/ops/first/one"].Modified, "Modified should be set on the page.")
+	assert.Empty(s.Pages["/ops/root"].Author, "Pages without history should be untouched.")
+	assert.Nil(s.Pages["/ops/root"].Modified, "Pages without history should be untouched.")
+}
+
 type walkerStruct struct {
 	Path    string
 	I       os.FileInfo
@@ -147,21 +214,37 @@ func TestAddToDir(t *testing.T) {
 			CurDirs:  []*Dir{},
 			AddPath:  "/readme",
 			OrigPath: "/readme",
-			ExpDirs:  []*Dir{&Dir{Icon: "note", IconAlt: "note", Path: "/readme", Text: "Readme"}},
+			ExpDirs:  []*Dir{&Dir{Icon: "note", IconAlt: "note", Name: "readme", Path: "/readme", Text: "Readme"}},
 			M:        "First dir should be added.",
 		},
+		{
+			CurDirs:  []*Dir{},
+			AddPath:  "/API/api-v2_overview",
+			OrigPath: "/API/api-v2_overview",
+			ExpDirs: []*Dir{
+				&Dir{Children: []*Dir{
+					&Dir{Icon: "note", IconAlt: "note", Name: "api-v2_overview", Path: "/API/api-v2_overview", Text: "Api V2 Overview"},
+				},
+					Icon:    "keyboard_arrow_up",
+					IconAlt: "keyboard_arrow_down",
+					Name:    "API",
+					Text:    "API"},
+			},
+			M: "Text should be made readable, keeping the name as is.",
+		},
 		{
 			CurDirs: []*Dir{
-				&Dir{Icon: "keyboard_arrow_up", IconAlt: "keyboard_arrow_down", Path: "/hello", Text: "Hello"},
+				&Dir{Icon: "keyboard_arrow_up", IconAlt: "keyboard_arrow_down", Name: "hello", Path: "/hello", Text: "Hello"},
 			},
 			AddPath:  "/hello/world",
 			OrigPath: "/hello/world",
 			ExpDirs: []*Dir{
 				&Dir{Children: []*Dir{
-					&Dir{Icon: "note", IconAlt: "note", Path: "/hello/world", Text: "World"},
+					&Dir{Icon: "note", IconAlt: "note", Name: "world", Path: "/hello/world", Text: "World"},
 				},
 					Icon:    "keyboard_arrow_up",
 					IconAlt: "keyboard_arrow_down",
+					Name:    "hello",
 					Path:    "/hello",
 					Text:    "Hello"},
 			},
@@ -187,7 +270,7 @@ func TestBuildPage(t *testing.T) {
 	assert := assert.New(t)
 	x := []pageStruct{
 		{
-			ExpPage: &autodocs.Page{Name: "root", Content: "<h1>root</h1>\n"},
+			ExpPage: &autodocs.Page{Name: "root", Content: "<h1>root</h1>\n", Title: "root"},
 			ExpErr:  nil,
 			InpPag:  "/root",
 			InpDir:  getTestMarkdownDir() + "root.md",
@@ -206,7 +289,7 @@ func TestBuildPage(t *testing.T) {
 	}
 
 	for _, a := range x {
-		actPage, actErr := buildPage(a.InpPag, a.InpDir)
+		actPage, actErr := buildPage(a.InpPag, osfs.New(filepath.Dir(a.InpDir)), filepath.Base(a.InpDir))
 		assert.Equal(a.ExpPage, actPage, a.M)
 		assert.Equal(a.ExpErr, actErr, a.M)
 	}
@@ -220,7 +303,7 @@ type dirStruct struct {
 	M      string
 }
 
-func TestDirHasText(t *testing.T) {
+func TestDirHasName(t *testing.T) {
 	assert := assert.New(t)
 	x := []dirStruct{
 		{
@@ -231,23 +314,23 @@ func TestDirHasText(t *testing.T) {
 			M:      "Empty list should fail",
 		},
 		{
-			ExpDir: &Dir{Text: "monkey"},
+			ExpDir: &Dir{Name: "monkey"},
 			ExpSuc: true,
-			InpDir: []*Dir{&Dir{Text: "monkey"}, &Dir{Text: "dishwasher"}},
+			InpDir: []*Dir{&Dir{Name: "monkey"}, &Dir{Name: "dishwasher"}},
 			InpStr: "monkey",
 			M:      "Matching dir should be returned",
 		},
 		{
 			ExpDir: nil,
 			ExpSuc: false,
-			InpDir: []*Dir{&Dir{Text: "monkey"}, &Dir{Text: "dishwasher"}},
+			InpDir: []*Dir{&Dir{Name: "monkey"}, &Dir{Name: "dishwasher"}},
 			InpStr: "purple",
-			M:      "Non-matching text should fail",
+			M:      "Non-matching name should fail",
 		},
 	}
 
 	for _, a := range x {
-		actDir, actSuc := dirHasText(a.InpDir, a.InpStr)
+		actDir, actSuc := dirHasName(a.InpDir, a.InpStr)
 		assert.Equal(a.ExpDir, actDir, a.M)
 		assert.Equal(a.ExpSuc, actSuc, a.M)
 	}
@@ -289,3 +372,124 @@ func getTestMarkdownDir() string {
 	wd, _ := os.Getwd()
 	return wd + "/testdata/"
 }
+
+func TestMountReindex(t *testing.T) {
+	assert := assert.New(t)
+	fs := memfs.New()
+	for n, c := range map[string]string{
+		"readme.md":          "# readme",
+		"old.md":             "# old",
+		"setup.md":           "# setup",
+		"guides/deploy.md":   "# deploy",
+		"guides/rollback.md": "# rollback",
+	} {
+		assert.Nil(util.WriteFile(fs, n, []byte(c), 0644))
+	}
+
+	s := NewStore()
+	assert.Equal(5, s.Mount("ops", fs, autodocs.Index{}))
+	s.Mount("other", osfs.New(getTestMarkdownDir()+"first/"), autodocs.Index{})
+
+	// delete a page, rename another and move a directory
+	assert.Nil(fs.Remove("old.md"))
+	assert.Nil(fs.Rename("setup.md", "install.md"))
+	assert.Nil(fs.MkdirAll("runbooks", 0755))
+	assert.Nil(fs.Rename("guides/deploy.md", "runbooks/deploy.md"))
+	assert.Nil(fs.Rename("guides/rollback.md", "runbooks/rollback.md"))
+	assert.Nil(fs.Remove("guides"))
+
+	assert.Equal(4, s.Mount("ops", fs, autodocs.Index{}), "Each page should be counted once.")
+	assert.ElementsMatch([]string{
+		"/ops", "/ops/install", "/ops/runbooks/deploy", "/ops/runbooks/rollback",
+		"/other/one", "/other/two",
+	}, keys(s.Pages), "Removed and moved pages should no longer be served.")
+	assert.Equal(map[string]string{
+		"/ops/setup":           "/ops/install",
+		"/ops/guides/deploy":   "/ops/runbooks/deploy",
+		"/ops/guides/rollback": "/ops/runbooks/rollback",
+	}, s.Redirects, "Moved pages should be redirected.")
+
+	assert.Equal(2, len(s.Dirs), "Each mount should remain a top level dir.")
+	ops, _ := dirHasName(s.Dirs, "ops")
+	assert.Equal("/ops", ops.Path, "A mount should lead to its landing page.")
+	dirs := []string{}
+	for _, d := range ops.Children {
+		dirs = append(dirs, d.Text)
+	}
+	assert.Equal([]string{"Runbooks", "setup"}, dirs, "Dirs should be built from scratch, titled by their pages.")
+
+	// a page changed as it moves is only known by its history
+	assert.Nil(fs.Rename("install.md", "installing.md"))
+	assert.Nil(util.WriteFile(fs, "installing.md", []byte("# installing"), 0644))
+	s.Redirect("ops", []autodocs.Change{
+		{Kind: autodocs.ChangeRenamed, From: "install.md", To: "installing.md"},
+		{Kind: autodocs.ChangeModified, From: "readme.md", To: "readme.md"},
+	})
+	s.Mount("ops", fs, autodocs.Index{})
+	assert.Equal("/ops/installing", s.Redirects["/ops/install"], "Renames should be redirected.")
+	assert.Equal("/ops/installing", s.Redirects["/ops/setup"], "Earlier moves should follow the rename.")
+
+	// a page removed after moving is no longer redirected to
+	assert.Nil(fs.Remove("installing.md"))
+	s.Mount("ops", fs, autodocs.Index{})
+	assert.NotContains(s.Redirects, "/ops/install", "A redirect to a page no longer served should be dropped.")
+	assert.NotContains(s.Redirects, "/ops/setup")
+
+	// a page served again at its old path is no longer redirected
+	assert.Nil(util.WriteFile(fs, "guides/deploy.md", []byte("# deploy v2"), 0644))
+	s.Mount("ops", fs, autodocs.Index{})
+	assert.NotContains(s.Redirects, "/ops/guides/deploy")
+	assert.Equal("/ops/runbooks/rollback", s.Redirects["/ops/guides/rollback"])
+}
+
+func TestMountLanding(t *testing.T) {
+	assert := assert.New(t)
+	fs := memfs.New()
+	for n, c := range map[string]string{
+		"README.md":        "# Welcome",
+		"guides/README.md": "---\ntitle: All guides\n---\nStart here.",
+		"guides/setup.md":  "# setup",
+		"runbooks/api.md":  "# api",
+	} {
+		assert.Nil(util.WriteFile(fs, n, []byte(c), 0644))
+	}
+
+	s := NewStore()
+	assert.Equal(4, s.Mount("", fs, autodocs.Index{}))
+	assert.Equal("Welcome", s.Pages["/"].Title, "The root landing page should be served from /.")
+	assert.Equal([]string{"guides", "runbooks"}, names(s.Dirs), "The root should not be a node of its own.")
+
+	g := s.Dirs[0]
+	assert.Equal("/guides", g.Path, "A directory should lead to its landing page.")
+	assert.Equal("All guides", g.Text, "A directory should be titled by its landing page.")
+	assert.Equal("keyboard_arrow_up", g.Icon, "A directory with a landing page should keep its children.")
+	assert.Equal([]string{"setup"}, names(g.Children))
+	assert.Equal("/runbooks", s.Dirs[1].Path, "A directory without a landing page should lead to its listing.")
+
+	assert.Nil(util.WriteFile(fs, "guides/README.md", []byte("# Guides"), 0644))
+	_, ok := s.Patch("", fs, []autodocs.Change{{Kind: autodocs.ChangeModified, From: "guides/README.md", To: "guides/README.md"}})
+	assert.True(ok, "A changed landing page should be patched.")
+
+	full := NewStore()
+	full.Mount("", fs, autodocs.Index{})
+	assert.Equal(full.Dirs, s.Dirs, "Patching a landing page should give the same tree as a full mount.")
+
+	assert.Nil(util.WriteFile(fs, "runbooks/index.md", []byte("# Runbooks"), 0644))
+	_, ok = s.Patch("", fs, []autodocs.Change{{Kind: autodocs.ChangeAdded, To: "runbooks/index.md"}})
+	assert.False(ok, "An added landing page should need a full mount.")
+
+	// a file beside a directory with a landing page shares its path
+	assert.Nil(util.WriteFile(fs, "guides.md", []byte("# Beside"), 0644))
+	_, ok = s.Patch("", fs, []autodocs.Change{{Kind: autodocs.ChangeAdded, To: "guides.md"}})
+	assert.False(ok, "A file added to a path already served should need a full mount.")
+
+	assert.Equal(5, s.Mount("", fs, autodocs.Index{}), "A file that is not served should not be counted.")
+	assert.Equal("Guides", s.Pages["/guides"].Title, "The landing page should be served over a file beside it.")
+
+	assert.Nil(fs.Remove("guides.md"))
+	_, ok = s.Patch("", fs, []autodocs.Change{{Kind: autodocs.ChangeRemoved, From: "guides.md"}})
+	assert.False(ok, "A file removed from a shared path should need a full mount.")
+
+	s.Mount("", fs, autodocs.Index{})
+	assert.Equal("Guides", s.Pages["/guides"].Title, "The landing page should still be served.")
+}
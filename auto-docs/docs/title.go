@@ -0,0 +1,62 @@
+package docs
+
+import (
+	"os"
+	"strings"
+	"unicode"
+
+	"gitlab.com/golang-commonmark/markdown"
+)
+
+// slug will turn each part of a path in to the form it is served
+// from, keeping the case as is and joining words with hyphens.
+func slug(p string) string {
+	b := strings.Split(p, string(os.PathSeparator))
+	for i, x := range b {
+		b[i] = strings.Join(strings.Fields(x), "-")
+	}
+
+	return strings.Join(b, string(os.PathSeparator))
+}
+
+// humanize will turn part of a path in to text to display, with each
+// hyphen or underscore as a space and each word capitalised.
+func humanize(n string) string {
+	w := strings.FieldsFunc(n, func(r rune) bool {
+		return r == '-' || r == '_' || unicode.IsSpace(r)
+	})
+
+	return strings.Title(strings.Join(w, " "))
+}
+
+// heading gives the plain text of the first top level heading within
+// the markdown, or empty when there is none.
+func heading(t []markdown.Token) string {
+	for i, x := range t {
+		h, ok := x.(*markdown.HeadingOpen)
+		if !ok || h.HLevel != 1 || i+1 >= len(t) {
+			continue
+		}
+
+		in, ok := t[i+1].(*markdown.Inline)
+		if !ok {
+			continue
+		}
+
+		r := ""
+		for _, c := range in.Children {
+			switch c := c.(type) {
+			case *markdown.Text:
+				r += c.Content
+			case *markdown.CodeInline:
+				r += c.Content
+			case *markdown.Softbreak, *markdown.Hardbreak:
+				r += " "
+			}
+		}
+
+		return strings.TrimSpace(r)
+	}
+
+	return ""
+}
@@ -0,0 +1,94 @@
+package docs
+
+import (
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+type titleStruct struct {
+	Path string
+	Inp  string
+	Exp  string
+	M    string
+}
+
+func TestTitle(t *testing.T) {
+	assert := assert.New(t)
+	x := []titleStruct{
+		{Path: "/api", Inp: "---\ntitle: The API\n---\n# api\n", Exp: "The API", M: "Front matter should give the title first."},
+		{Path: "/api", Inp: "intro\n\n## Usage\n\n# The `api` *v2*\n", Exp: "The api v2", M: "The first top level heading should be the title, as plain text."},
+		{Path: "/api-v2_overview", Inp: "no heading\n", Exp: "Api V2 Overview", M: "The name should be the title without a heading."},
+		{Path: "/api", Inp: "", Exp: "Api", M: "An empty page should be titled by its name."},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, NewPage(a.Path, []byte(a.Inp)).Title, a.M)
+	}
+}
+
+type slugStruct struct {
+	Inp string
+	Exp string
+	M   string
+}
+
+func TestSlug(t *testing.T) {
+	assert := assert.New(t)
+	x := []slugStruct{
+		{Inp: "API/Overview", Exp: "API/Overview", M: "Case should be kept."},
+		{Inp: "Getting Started/First  steps ", Exp: "Getting-Started/First-steps", M: "Spaces should become hyphens."},
+		{Inp: "api-v2_overview", Exp: "api-v2_overview", M: "Hyphens and underscores should be kept."},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, slug(a.Inp), a.M)
+	}
+}
+
+func TestHumanize(t *testing.T) {
+	assert := assert.New(t)
+	x := []slugStruct{
+		{Inp: "api-v2_overview", Exp: "Api V2 Overview", M: "Hyphens and underscores should become spaces."},
+		{Inp: "API", Exp: "API", M: "Capitals should be kept."},
+		{Inp: "--a__b--", Exp: "A B", M: "Repeated separators should become one space."},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, humanize(a.Inp), a.M)
+	}
+}
+
+func TestPageLookup(t *testing.T) {
+	assert := assert.New(t)
+	fs := memfs.New()
+	for n, c := range map[string]string{
+		"API/Getting Started.md": "# Getting started with the API",
+		"API/old.md":             "# old",
+	} {
+		assert.Nil(util.WriteFile(fs, n, []byte(c), 0644))
+	}
+
+	s := NewStore()
+	s.Mount("", fs, autodocs.Index{})
+	assert.Contains(s.Pages, "/API/Getting-Started", "The path should keep its case.")
+
+	pg, ok := s.Page("/api/getting-started")
+	assert.True(ok, "A page should be found regardless of case.")
+	assert.Equal("Getting started with the API", pg.Title)
+
+	_, ok = s.Page("/api/missing")
+	assert.False(ok, "An unknown page should not be found.")
+
+	assert.Equal("API", s.Dirs[0].Text, "A directory should be titled by its name.")
+	assert.Equal("Getting started with the API", s.Dirs[0].Children[0].Text, "A page should be titled by its heading.")
+
+	assert.Nil(fs.Rename("API/old.md", "API/new.md"))
+	s.Mount("", fs, autodocs.Index{})
+	to, ok := s.Moved("/api/OLD")
+	assert.True(ok, "A moved page should be found regardless of case.")
+	assert.Equal("/API/new", to)
+}
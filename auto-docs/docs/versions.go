@@ -0,0 +1,54 @@
+package docs
+
+import (
+	"sync"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+)
+
+// Versions holds a separate Store for each named version.
+type Versions struct {
+	// mu guards stores.
+	mu sync.RWMutex
+
+	// stores captures the Store for each version name.
+	stores map[string]*Store
+}
+
+// NewVersions gives an empty Versions.
+func NewVersions() *Versions {
+	return &Versions{stores: map[string]*Store{}}
+}
+
+// NewStore gives an empty Store ready to be loaded.
+func NewStore() *Store {
+	return &Store{
+		Dirs:  []*Dir{},
+		Pages: make(map[string]*autodocs.Page),
+	}
+}
+
+// Get will find the Store for a version.
+func (v *Versions) Get(n string) (*Store, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	s, ok := v.stores[n]
+	return s, ok
+}
+
+// Set will replace the Store for a version.
+func (v *Versions) Set(n string, s *Store) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.stores[n] = s
+}
+
+// Delete will remove the Store for a version.
+func (v *Versions) Delete(n string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.stores, n)
+}
@@ -43,10 +43,13 @@ func init() {
 	viper.SetDefault("Git.SSHKey", "/var/auto-docs/keys/id_rsa")
 	viper.SetDefault("Git.Branch", "master")
 	viper.SetDefault("Git.LocalPath", "/tmp/auto-docs")
-	viper.SetDefault("Git.Timeout", "1500")
+	viper.SetDefault("Git.Timeout", "30000")
+	viper.SetDefault("Git.CloneTimeout", "600000")
 	viper.SetDefault("Git.Period", "300")
+	viper.SetDefault("Local.Period", "300")
 	viper.SetDefault("Listen", ":9003")
 	viper.SetDefault("Name", "auto-docs")
+	viper.SetDefault("Source", "git")
 }
 
 func main() {
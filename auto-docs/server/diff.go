@@ -0,0 +1,132 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/cloudcloud/auto-docs/auto-docs/data"
+	"github.com/cloudcloud/auto-docs/auto-docs/docs"
+	"github.com/gin-gonic/gin"
+)
+
+// diff will compare a single page between two revisions, or list
+// each page that changed under the path when it is not a page or the
+// pages are asked for.
+func (s *Server) diff(c *gin.Context) {
+	p := c.Param("path")
+	y, h := s.historic(p)
+	if h == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "History not available"})
+		return
+	}
+
+	if c.Query("from") == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A from revision is required"})
+		return
+	}
+	from, err := h.Resolve(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	to, err := h.Resolve(c.DefaultQuery("to", y.source.Revision()))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	changes, err := h.Changes(from, to)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to compare revisions"})
+		return
+	}
+
+	// a directory with a landing page is a page as well, so listing
+	// the pages under it has to be asked for
+	var a, b string
+	var aok, bok bool
+	if c.Query("pages") == "" {
+		a, aok = fileFor(h, from, y.config, p)
+		b, bok = fileFor(h, to, y.config, p)
+	}
+	if !aok && !bok {
+		c.JSON(http.StatusOK, gin.H{
+			"from":  from,
+			"to":    to,
+			"pages": pageChanges(h, changes, from, to, y.config, p),
+		})
+		return
+	}
+
+	// follow the page across a rename between the revisions
+	for _, x := range changes {
+		if x.Kind != autodocs.ChangeRenamed {
+			continue
+		}
+		if !aok && x.To == b {
+			a, aok = x.From, true
+		}
+		if !bok && x.From == a {
+			b, bok = x.To, true
+		}
+	}
+
+	before, err := readAt(h, from, a, aok)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to read page"})
+		return
+	}
+	after, err := readAt(h, to, b, bok)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to read page"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"from":    from,
+		"to":      to,
+		"path":    p,
+		"unified": docs.Unified(a, b, before, after),
+		"html":    docs.WordDiff(before, after),
+	})
+}
+
+// pageChanges will give the changes to served files as page paths,
+// for the pages under the path.
+func pageChanges(h data.Historic, changes []autodocs.Change, from, to string, c autodocs.Source, p string) []autodocs.Change {
+	r := []autodocs.Change{}
+	before, _, aok := filterAt(h, from, c)
+	after, _, bok := filterAt(h, to, c)
+	if !aok || !bok {
+		return r
+	}
+
+	p = strings.ToLower(p)
+	under := func(k string) bool {
+		k = strings.ToLower(k)
+		return k != "" && (p == "/" || k == p || strings.HasPrefix(k, strings.TrimSuffix(p, "/")+"/"))
+	}
+
+	for _, x := range changes {
+		a, _ := before.Key(c.Mount, x.From)
+		b, _ := after.Key(c.Mount, x.To)
+		if !under(a) && !under(b) {
+			continue
+		}
+
+		r = append(r, autodocs.Change{Kind: x.Kind, From: a, To: b})
+	}
+
+	return r
+}
+
+// readAt will read the file at the revision, giving no content when
+// the file does not exist there.
+func readAt(h data.Historic, rev, f string, ok bool) ([]byte, error) {
+	if !ok {
+		return []byte{}, nil
+	}
+
+	return h.ReadFile(rev, f)
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+type diffStruct struct {
+	Path    string
+	ExpCode int
+	ExpBody string
+	M       string
+}
+
+func TestDiff(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	h := &fakeHistory{
+		revs: map[string]map[string]string{
+			"def456": {"restart.md": "# restart\n\nold\n", "gone.md": "# gone\n", "same.md": "# same\n", "README.md": "# ops\n"},
+			"abc123": {"Runbooks/Restart.md": "# restart\n\nnew\n", "added.md": "# added\n", "same.md": "# same\n", "README.md": "# ops\n\nnew\n"},
+		},
+		changes: []autodocs.Change{
+			{Kind: autodocs.ChangeAdded, To: "added.md"},
+			{Kind: autodocs.ChangeRemoved, From: "gone.md"},
+			{Kind: autodocs.ChangeRenamed, From: "restart.md", To: "Runbooks/Restart.md"},
+			{Kind: autodocs.ChangeModified, From: "README.md", To: "README.md"},
+			{Kind: autodocs.ChangeModified, From: "readme.txt", To: "readme.txt"},
+		},
+	}
+	s := &Server{Engine: gin.New(), syncers: []*syncer{
+		{config: autodocs.Source{Mount: "/ops"}, source: h},
+		{config: autodocs.Source{Mount: "/other"}, source: &fakeSource{}},
+	}}
+	s.addAPI()
+
+	x := []diffStruct{
+		{
+			Path:    "/_api/diff/ops/runbooks/restart?from=def456&to=abc123",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"from":"def456","to":"abc123","path":"/ops/runbooks/restart",` +
+				`"unified":"--- a/restart.md\n+++ b/Runbooks/Restart.md\n@@ -1,3 +1,3 @@\n # restart\n \n-old\n+new\n",` +
+				`"html":"<h1>restart</h1>\n<p><del>old</del><ins>new</ins></p>\n"}`,
+			M: "A page should be compared across a rename.",
+		},
+		{
+			Path:    "/_api/diff/ops/added?from=def456",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"from":"def456","to":"abc123","path":"/ops/added",` +
+				`"unified":"--- /dev/null\n+++ b/added.md\n@@ -0,0 +1 @@\n+# added\n",` +
+				`"html":"<h1><ins>added</ins></h1>\n"}`,
+			M: "The later revision should default to the current one.",
+		},
+		{
+			Path:    "/_api/diff/ops?from=def456&to=abc123&pages=1",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"from":"def456","to":"abc123","pages":[` +
+				`{"kind":"added","to":"/ops/added"},` +
+				`{"kind":"removed","from":"/ops/gone"},` +
+				`{"kind":"renamed","from":"/ops/restart","to":"/ops/Runbooks/Restart"},` +
+				`{"kind":"modified","from":"/ops","to":"/ops"}]}`,
+			M: "Each changed page should be listed for the site, even with a root README.",
+		},
+		{
+			Path:    "/_api/diff/ops?from=def456&to=abc123",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"from":"def456","to":"abc123","path":"/ops",` +
+				`"unified":"--- a/README.md\n+++ b/README.md\n@@ -1 +1,3 @@\n # ops\n+\n+new\n",` +
+				`"html":"<h1>ops</h1>\n<p><ins>new</ins></p>\n"}`,
+			M: "A root README should be compared as the landing page of the site.",
+		},
+		{
+			Path:    "/_api/diff/ops/runbooks?from=def456&to=abc123",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"from":"def456","to":"abc123","pages":[` +
+				`{"kind":"renamed","from":"/ops/restart","to":"/ops/Runbooks/Restart"}]}`,
+			M: "Only changed pages under the path should be listed.",
+		},
+		{
+			Path:    "/_api/diff/ops/runbooks/restart?to=abc123",
+			ExpCode: http.StatusBadRequest,
+			ExpBody: `{"error":"A from revision is required"}`,
+			M:       "The earlier revision should be required.",
+		},
+		{
+			Path:    "/_api/diff/ops/runbooks/restart?from=nothing",
+			ExpCode: http.StatusNotFound,
+			ExpBody: `{"error":"Revision not found"}`,
+			M:       "An unknown revision should not be found.",
+		},
+		{
+			Path:    "/_api/diff/other/page?from=def456",
+			ExpCode: http.StatusNotFound,
+			ExpBody: `{"error":"History not available"}`,
+			M:       "A source without history should not be found.",
+		},
+	}
+
+	for _, a := range x {
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, a.Path, nil))
+
+		assert.Equal(a.ExpCode, w.Code, a.M)
+		assert.JSONEq(a.ExpBody, w.Body.String(), a.M)
+	}
+}
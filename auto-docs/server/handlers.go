@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"net/http"
 
-	"github.com/cloudcloud/auto-docs/auto-docs/docs"
 	assetfs "github.com/elazarl/go-bindata-assetfs"
 	"github.com/gin-gonic/gin"
 )
@@ -23,24 +22,48 @@ func handleFiles(e *gin.Engine) {
 }
 
 // health checks the state of auto-docs and provides a
-// response based on this.
-func health(c *gin.Context) {
+// response based on this. Sources that are failing to sync are
+// reported as degraded, while the last good content is served.
+func (s *Server) health(c *gin.Context) {
+	errs := map[string]string{}
+	for _, y := range s.syncers {
+		if x := y.state(); x.Failures > 0 {
+			errs[y.config.Name] = x.LastError
+		}
+	}
+
+	if len(errs) > 0 {
+		c.JSON(http.StatusOK, gin.H{"status": "degraded", "errors": errs})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{"status": "ok"})
 }
 
-// page will retrieve the data for a single page.
-func page(c *gin.Context) {
-	if p, ok := docs.S.Pages[c.Param("path")]; !ok {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Path not found"})
-	} else {
+// page will retrieve the data for a single page. When a ref is
+// provided, the page is loaded as it was at that revision. A page
+// that has since moved is redirected to, only temporarily as the old
+// path may be served again.
+func (s *Server) page(c *gin.Context) {
+	if ref := c.Query("ref"); ref != "" {
+		s.pageAt(c, c.Param("path"), ref)
+		return
+	}
+
+	st := s.Docs.Load()
+	if p, ok := st.Page(c.Param("path")); ok {
 		c.JSON(http.StatusOK, p)
+	} else if to, ok := st.Moved(c.Param("path")); ok {
+		c.Redirect(http.StatusTemporaryRedirect, "/_api/page"+to)
+	} else {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Path not found"})
 	}
 }
 
 // pages will provide a full list of the tree structure
 // of pages currently available.
-func pages(c *gin.Context) {
-	c.JSON(http.StatusOK, docs.S)
+func (s *Server) pages(c *gin.Context) {
+	c.JSON(http.StatusOK, s.Docs.Load())
 }
 
 // root will serve the base shell, and then filter out
@@ -1 +1,52 @@
 package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/cloudcloud/auto-docs/auto-docs/docs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+type pageStruct struct {
+	Path        string
+	ExpCode     int
+	ExpLocation string
+	M           string
+}
+
+func TestPage(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	fs := memfs.New()
+	assert.Nil(util.WriteFile(fs, "setup.md", []byte("# setup"), 0644))
+
+	s := &Server{Docs: docs.NewLive(), Engine: gin.New()}
+	s.Docs.Update(func(st *docs.Store) { st.Mount("ops", fs, autodocs.Index{}) })
+	assert.Nil(fs.Rename("setup.md", "install.md"))
+	s.Docs.Update(func(st *docs.Store) { st.Mount("ops", fs, autodocs.Index{}) })
+	s.addAPI()
+
+	x := []pageStruct{
+		{Path: "/_api/page/ops/install", ExpCode: http.StatusOK, M: "A page should be served."},
+		{Path: "/_api/page/ops/setup", ExpCode: http.StatusTemporaryRedirect, ExpLocation: "/_api/page/ops/install", M: "A moved page should be redirected."},
+		{Path: "/_api/page/Ops/Install", ExpCode: http.StatusOK, M: "A page should be found regardless of case."},
+		{Path: "/_api/page/ops/SETUP", ExpCode: http.StatusTemporaryRedirect, ExpLocation: "/_api/page/ops/install", M: "A moved page should be found regardless of case."},
+		{Path: "/_api/page/ops", ExpCode: http.StatusOK, M: "A directory should be served a listing."},
+		{Path: "/_api/page/ops/missing", ExpCode: http.StatusNotFound, M: "An unknown page should not be found."},
+	}
+
+	for _, a := range x {
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, a.Path, nil))
+
+		assert.Equal(a.ExpCode, w.Code, a.M)
+		assert.Equal(a.ExpLocation, w.Header().Get("Location"), a.M)
+	}
+}
@@ -0,0 +1,129 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/cloudcloud/auto-docs/auto-docs/data"
+	"github.com/cloudcloud/auto-docs/auto-docs/docs"
+	"github.com/gin-gonic/gin"
+)
+
+// history will list each commit that changed a single page.
+func (s *Server) history(c *gin.Context) {
+	p := c.Param("path")
+	y, h := s.historic(p)
+	if h == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "History not available"})
+		return
+	}
+
+	rev := y.source.Revision()
+	f, ok := fileFor(h, rev, y.config, p)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Path not found"})
+		return
+	}
+
+	l, err := h.Log(rev, f)
+	if err == data.ErrNoHistory {
+		c.JSON(http.StatusNotFound, gin.H{"error": "History not available"})
+		return
+	} else if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to read history"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"commits": l})
+}
+
+// pageAt will load a single page as it was at a past revision,
+// reading from the history of the source rather than the store.
+func (s *Server) pageAt(c *gin.Context, p, ref string) {
+	y, h := s.historic(p)
+	if h == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "History not available"})
+		return
+	}
+
+	rev, err := h.Resolve(ref)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	f, ok := fileFor(h, rev, y.config, p)
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Path not found"})
+		return
+	}
+
+	b, err := h.ReadFile(rev, f)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Unable to read page"})
+		return
+	}
+
+	pg := docs.NewPage(p, b)
+	pg.Permalink = "/_api/page" + p + "?ref=" + url.QueryEscape(rev)
+	c.JSON(http.StatusOK, pg)
+}
+
+// historic will find the source that a page path is mounted from,
+// when that source is able to provide history.
+func (s *Server) historic(p string) (*syncer, data.Historic) {
+	var found *syncer
+	for _, y := range s.syncers {
+		m := strings.ToLower(docs.Key(y.config.Mount, ""))
+		if l := strings.ToLower(p); m != "/" && l != m && !strings.HasPrefix(l, m+"/") {
+			continue
+		}
+		if found == nil || len(m) > len(docs.Key(found.config.Mount, "")) {
+			found = y
+		}
+	}
+
+	if found == nil {
+		return nil, nil
+	}
+	h, ok := found.source.(data.Historic)
+	if !ok {
+		return nil, nil
+	}
+
+	return found, h
+}
+
+// fileFor will find the markdown file at the revision that is
+// served from the page path.
+func fileFor(h data.Historic, rev string, c autodocs.Source, p string) (string, bool) {
+	f, files, ok := filterAt(h, rev, c)
+	if !ok {
+		return "", false
+	}
+
+	for _, x := range files {
+		if k, ok := f.Key(c.Mount, x); ok && strings.EqualFold(k, p) {
+			return x, true
+		}
+	}
+
+	return "", false
+}
+
+// filterAt will build the Filter for the files of a source as they
+// were at the revision.
+func filterAt(h data.Historic, rev string, c autodocs.Source) (*docs.Filter, []string, bool) {
+	files, err := h.Files(rev)
+	if err != nil {
+		return nil, nil, false
+	}
+
+	f := docs.NewFilter(c.Index, files, func(x string) ([]byte, error) {
+		return h.ReadFile(rev, x)
+	})
+
+	return f, files, true
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/cloudcloud/auto-docs/auto-docs/data"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeHistory is a data.Source with history for a set of files
+// at each revision.
+type fakeHistory struct {
+	fakeSource
+
+	revs map[string]map[string]string
+
+	log []autodocs.Commit
+
+	changes []autodocs.Change
+
+	asked []string
+}
+
+func (f *fakeHistory) Resolve(ref string) (string, error) {
+	if _, ok := f.revs[ref]; !ok {
+		return "", fmt.Errorf("unknown revision: %s", ref)
+	}
+	return ref, nil
+}
+func (f *fakeHistory) Files(rev string) ([]string, error) {
+	r := []string{}
+	for n := range f.revs[rev] {
+		r = append(r, n)
+	}
+	return r, nil
+}
+func (f *fakeHistory) ReadFile(rev, p string) ([]byte, error) {
+	return []byte(f.revs[rev][p]), nil
+}
+
+func (f *fakeHistory) Revision() string { return "abc123" }
+func (f *fakeHistory) Log(rev, p string) ([]autodocs.Commit, error) {
+	if f.log == nil {
+		return nil, data.ErrNoHistory
+	}
+	return f.log, nil
+}
+func (f *fakeHistory) LastModified(rev string, files []string) (map[string]autodocs.Commit, error) {
+	if f.log == nil {
+		return nil, data.ErrNoHistory
+	}
+	f.asked = files
+	return map[string]autodocs.Commit{"Runbooks/Restart.md": f.log[0]}, nil
+}
+
+func (f *fakeHistory) Changes(from, to string) ([]autodocs.Change, error) {
+	return f.changes, nil
+}
+
+type pageAtStruct struct {
+	Path    string
+	ExpCode int
+	ExpBody string
+	M       string
+}
+
+func TestPageAt(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	h := &fakeHistory{revs: map[string]map[string]string{
+		"abc123": {"Runbooks/Restart.md": "# restart", "readme.txt": "no"},
+	}}
+	s := &Server{Engine: gin.New(), syncers: []*syncer{
+		{config: autodocs.Source{Mount: "/ops"}, source: h},
+		{config: autodocs.Source{Mount: "/other"}, source: &fakeSource{}},
+	}}
+	s.addAPI()
+
+	x := []pageAtStruct{
+		{
+			Path:    "/_api/page/ops/runbooks/restart?ref=abc123",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"name":"restart","content":"<h1>restart</h1>\n","title":"restart","permalink":"/_api/page/ops/runbooks/restart?ref=abc123"}`,
+			M:       "A page should be read from the revision.",
+		},
+		{
+			Path:    "/_api/page/ops/runbooks/missing?ref=abc123",
+			ExpCode: http.StatusNotFound,
+			ExpBody: `{"error":"Path not found"}`,
+			M:       "A page missing at the revision should not be found.",
+		},
+		{
+			Path:    "/_api/page/ops/runbooks/restart?ref=def456",
+			ExpCode: http.StatusNotFound,
+			ExpBody: `{"error":"Revision not found"}`,
+			M:       "An unknown revision should not be found.",
+		},
+		{
+			Path:    "/_api/page/other/page?ref=abc123",
+			ExpCode: http.StatusNotFound,
+			ExpBody: `{"error":"History not available"}`,
+			M:       "A source without history should not be found.",
+		},
+	}
+
+	for _, a := range x {
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, a.Path, nil))
+
+		assert.Equal(a.ExpCode, w.Code, a.M)
+		assert.JSONEq(a.ExpBody, w.Body.String(), a.M)
+	}
+}
+
+type historyStruct struct {
+	Path    string
+	Log     []autodocs.Commit
+	ExpCode int
+	ExpBody string
+	M       string
+}
+
+func TestHistory(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	when := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	log := []autodocs.Commit{
+		{Author: "Ann", Date: when, Email: "ann@example.com", Message: "Move restart", Path: "Runbooks/Restart.md", Sha: "abc123"},
+		{Author: "Bo", Date: when.AddDate(0, 0, -1), Email: "bo@example.com", Message: "Add restart", Path: "restart.md", Sha: "def456"},
+	}
+	x := []historyStruct{
+		{
+			Path:    "/_api/history/ops/runbooks/restart",
+			Log:     log,
+			ExpCode: http.StatusOK,
+			ExpBody: `{"commits":[` +
+				`{"author":"Ann","date":"2020-06-01T12:00:00Z","email":"ann@example.com","message":"Move restart","path":"Runbooks/Restart.md","sha":"abc123"},` +
+				`{"author":"Bo","date":"2020-05-31T12:00:00Z","email":"bo@example.com","message":"Add restart","path":"restart.md","sha":"def456"}]}`,
+			M: "Each commit to the page should be listed.",
+		},
+		{
+			Path:    "/_api/history/ops/runbooks/missing",
+			Log:     log,
+			ExpCode: http.StatusNotFound,
+			ExpBody: `{"error":"Path not found"}`,
+			M:       "A missing page should not be found.",
+		},
+		{
+			Path:    "/_api/history/ops/runbooks/restart",
+			Log:     nil,
+			ExpCode: http.StatusNotFound,
+			ExpBody: `{"error":"History not available"}`,
+			M:       "A source without history enabled should not be found.",
+		},
+	}
+
+	for _, a := range x {
+		h := &fakeHistory{
+			revs: map[string]map[string]string{"abc123": {"Runbooks/Restart.md": "# restart"}},
+			log:  a.Log,
+		}
+		s := &Server{Engine: gin.New(), syncers: []*syncer{{config: autodocs.Source{Mount: "/ops"}, source: h}}}
+		s.addAPI()
+
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, a.Path, nil))
+
+		assert.Equal(a.ExpCode, w.Code, a.M)
+		assert.JSONEq(a.ExpBody, w.Body.String(), a.M)
+	}
+
+	f := &fakeHistory{log: log}
+	y := &syncer{config: autodocs.Source{Mount: "/ops"}, source: f}
+	changes := []autodocs.Change{
+		{Kind: autodocs.ChangeRenamed, From: "restart.md", To: "Runbooks/Restart.md"},
+		{Kind: autodocs.ChangeRemoved, From: "gone.md"},
+	}
+	assert.Equal(map[string]autodocs.Commit{"Runbooks/Restart.md": log[0]}, y.lastModified(changes))
+	assert.Nil(f.asked, "Every file should be looked up the first time.")
+
+	y.info = map[string]autodocs.Commit{"restart.md": log[1], "gone.md": log[1], "same.md": log[1]}
+	assert.Equal(
+		map[string]autodocs.Commit{"Runbooks/Restart.md": log[0], "same.md": log[1]},
+		y.lastModified(changes),
+		"Only changed files should be updated, keeping the rest.",
+	)
+	assert.Equal([]string{"Runbooks/Restart.md"}, f.asked, "Only changed files should be looked up.")
+
+	y.lastModified(nil)
+	assert.Nil(f.asked, "Every file should be looked up without changes.")
+}
+
+func TestChanges(t *testing.T) {
+	assert := assert.New(t)
+	changes := []autodocs.Change{{Kind: autodocs.ChangeRenamed, From: "restart.md", To: "Runbooks/Restart.md"}}
+	y := &syncer{config: autodocs.Source{Mount: "/ops"}, source: &fakeHistory{changes: changes}}
+
+	assert.Nil(y.changes(), "A source never synced should have no changes.")
+
+	y.status.Revision = "abc123"
+	assert.Nil(y.changes(), "An unchanged revision should have no changes.")
+
+	y.status.Revision = "def456"
+	assert.Equal(changes, y.changes(), "Changes since the last revision should be given.")
+
+	y.config.Git.Submodules = true
+	assert.Nil(y.changes(), "Sources with submodules should not be compared.")
+}
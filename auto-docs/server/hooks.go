@@ -0,0 +1,182 @@
+package server
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"hash"
+	"io/ioutil"
+	"net/http"
+	"strings"
+
+	"github.com/cloudcloud/auto-docs/auto-docs/data"
+	"github.com/gin-gonic/gin"
+)
+
+// maxHookSize is the largest push payload that will be accepted.
+const maxHookSize = 5 << 20
+
+// pushEvent captures the parts of a GitHub, GitLab or Gitea push
+// payload needed to decide which source to sync.
+type pushEvent struct {
+	// Ref is the full reference that was pushed to.
+	Ref string `json:"ref"`
+
+	// Project holds the GitLab repository details.
+	Project pushRepo `json:"project"`
+
+	// Repository holds the repository details for all hosts.
+	Repository pushRepo `json:"repository"`
+}
+
+// pushRepo lists the remote URIs a host may give for a repository.
+type pushRepo struct {
+	CloneURL   string `json:"clone_url"`
+	GitURL     string `json:"git_url"`
+	GitHTTPURL string `json:"git_http_url"`
+	GitSSHURL  string `json:"git_ssh_url"`
+	SSHURL     string `json:"ssh_url"`
+}
+
+// remotes gives each non-empty URI within the event.
+func (p pushEvent) remotes() []string {
+	r := []string{}
+	for _, x := range []pushRepo{p.Repository, p.Project} {
+		for _, u := range []string{x.CloneURL, x.GitURL, x.GitHTTPURL, x.GitSSHURL, x.SSHURL} {
+			if u != "" {
+				r = append(r, u)
+			}
+		}
+	}
+
+	return r
+}
+
+// hook will accept a push webhook, triggering a sync of each git
+// source that the push applies to.
+func (s *Server) hook(c *gin.Context) {
+	if isOtherEvent(c.Request.Header) {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+		return
+	}
+
+	b, err := ioutil.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxHookSize))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unable to read payload"})
+		return
+	}
+
+	p := pushEvent{}
+	if err := json.Unmarshal(b, &p); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid payload"})
+		return
+	}
+
+	ys := s.hookSyncers(p.remotes())
+	if len(ys) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No matching source"})
+		return
+	}
+
+	verified, queued := false, []string{}
+	for _, y := range ys {
+		if !verifyHook(y.config.Git.Secret, c.Request.Header, b) {
+			continue
+		}
+
+		verified = true
+		if data.Follows(y.config.Git, p.Ref) {
+			y.notify()
+			queued = append(queued, y.config.Name)
+		}
+	}
+
+	if !verified {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid signature"})
+	} else if len(queued) == 0 {
+		c.JSON(http.StatusOK, gin.H{"status": "ignored"})
+	} else {
+		c.JSON(http.StatusAccepted, gin.H{"status": "queued", "sources": queued})
+	}
+}
+
+// hookSyncers will find the git sources for the pushed remotes.
+// When the payload matches none, a lone git source is assumed.
+func (s *Server) hookSyncers(remotes []string) []*syncer {
+	all, ys := []*syncer{}, []*syncer{}
+	for _, y := range s.syncers {
+		if !y.isGit() {
+			continue
+		}
+		all = append(all, y)
+
+		for _, r := range remotes {
+			if data.SameRemote(y.config.Git.URI, r) {
+				ys = append(ys, y)
+				break
+			}
+		}
+	}
+
+	if len(ys) == 0 && len(all) == 1 {
+		return all
+	}
+
+	return ys
+}
+
+// isOtherEvent will check the event headers from each host for
+// anything that is not a push, such as a ping.
+func isOtherEvent(h http.Header) bool {
+	if e := h.Get("X-GitHub-Event"); e != "" {
+		return e != "push"
+	}
+	if e := h.Get("X-Gitea-Event"); e != "" {
+		return e != "push"
+	}
+	if e := h.Get("X-Gitlab-Event"); e != "" {
+		return e != "Push Hook"
+	}
+
+	return false
+}
+
+// verifyHook will check the payload against the shared secret,
+// using the signature or token header provided by the host.
+func verifyHook(secret string, h http.Header, b []byte) bool {
+	if secret == "" {
+		return false
+	}
+
+	if x := h.Get("X-Hub-Signature-256"); x != "" {
+		return validMAC(sha256.New, secret, strings.TrimPrefix(x, "sha256="), b)
+	}
+	if x := h.Get("X-Hub-Signature"); x != "" {
+		return validMAC(sha1.New, secret, strings.TrimPrefix(x, "sha1="), b)
+	}
+	if x := h.Get("X-Gitea-Signature"); x != "" {
+		return validMAC(sha256.New, secret, x, b)
+	}
+	if x := h.Get("X-Gitlab-Token"); x != "" {
+		return subtle.ConstantTimeCompare([]byte(x), []byte(secret)) == 1
+	}
+
+	return false
+}
+
+// validMAC will compare a hex encoded signature against the HMAC
+// of the payload.
+func validMAC(f func() hash.Hash, secret, sig string, b []byte) bool {
+	exp, err := hex.DecodeString(sig)
+	if err != nil {
+		return false
+	}
+
+	m := hmac.New(f, []byte(secret))
+	m.Write(b)
+
+	return hmac.Equal(exp, m.Sum(nil))
+}
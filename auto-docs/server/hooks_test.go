@@ -0,0 +1,174 @@
+package server
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/cloudcloud/auto-docs/auto-docs/docs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+)
+
+// fakeSource is a data.Source that counts each fetch.
+type fakeSource struct {
+	fetches int32
+}
+
+func (f *fakeSource) Prepare() error { return nil }
+func (f *fakeSource) Fetch(t time.Time) (bool, error) {
+	atomic.AddInt32(&f.fetches, 1)
+	return false, nil
+}
+func (f *fakeSource) Revision() string     { return "" }
+func (f *fakeSource) FS() billy.Filesystem { return memfs.New() }
+
+func newHookSyncer(name, uri string) (*syncer, *fakeSource) {
+	f := &fakeSource{}
+	return &syncer{
+		config: autodocs.Source{
+			Git:  autodocs.Git{Branch: "master", Period: 3600, Secret: "s3cret", Tags: []string{"v*"}, URI: uri},
+			Name: name,
+		},
+		source:  f,
+		mu:      &sync.Mutex{},
+		live:    docs.NewLive(),
+		trigger: make(chan struct{}, 1),
+		delay:   50 * time.Millisecond,
+	}, f
+}
+
+func sign(secret, body string) string {
+	m := hmac.New(sha256.New, []byte(secret))
+	m.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(m.Sum(nil))
+}
+
+type hookStruct struct {
+	Body    string
+	Headers map[string]string
+	ExpCode int
+	ExpA    int32
+	ExpB    int32
+	M       string
+}
+
+func TestHook(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	pushA := `{"ref":"refs/heads/master","repository":{"clone_url":"https://example.com/org/a.git"}}`
+	pushB := `{"ref":"refs/heads/master","project":{"git_ssh_url":"git@example.com:org/b.git"}}`
+	other := `{"ref":"refs/heads/feature","repository":{"ssh_url":"git@example.com:org/a.git"}}`
+	tag := `{"ref":"refs/tags/v1.0","repository":{"ssh_url":"git@example.com:org/a.git"}}`
+	x := []hookStruct{
+		{
+			Body:    pushA,
+			Headers: map[string]string{"X-GitHub-Event": "push", "X-Hub-Signature-256": sign("s3cret", pushA)},
+			ExpCode: http.StatusAccepted,
+			ExpA:    1,
+			M:       "A signed GitHub push should sync the matching source.",
+		},
+		{
+			Body:    pushB,
+			Headers: map[string]string{"X-Gitlab-Event": "Push Hook", "X-Gitlab-Token": "s3cret"},
+			ExpCode: http.StatusAccepted,
+			ExpB:    1,
+			M:       "A GitLab push with the token should sync the matching source.",
+		},
+		{
+			Body:    pushA,
+			Headers: map[string]string{"X-Gitea-Event": "push", "X-Gitea-Signature": sign("s3cret", pushA)[7:]},
+			ExpCode: http.StatusAccepted,
+			ExpA:    1,
+			M:       "A signed Gitea push should sync the matching source.",
+		},
+		{
+			Body:    pushA,
+			Headers: map[string]string{"X-Hub-Signature-256": sign("wrong", pushA)},
+			ExpCode: http.StatusUnauthorized,
+			M:       "An invalid signature should be refused.",
+		},
+		{
+			Body:    other,
+			Headers: map[string]string{"X-Hub-Signature-256": sign("s3cret", other)},
+			ExpCode: http.StatusOK,
+			M:       "A push to another branch should be ignored.",
+		},
+		{
+			Body:    tag,
+			Headers: map[string]string{"X-Hub-Signature-256": sign("s3cret", tag)},
+			ExpCode: http.StatusAccepted,
+			ExpA:    1,
+			M:       "A push of a tag that is a version should sync the source.",
+		},
+		{
+			Body:    `{"zen":"hello"}`,
+			Headers: map[string]string{"X-GitHub-Event": "ping"},
+			ExpCode: http.StatusOK,
+			M:       "A ping should be ignored.",
+		},
+		{
+			Body:    `{"ref":"refs/heads/master","repository":{"clone_url":"https://example.com/org/c.git"}}`,
+			Headers: map[string]string{},
+			ExpCode: http.StatusNotFound,
+			M:       "An unknown repository should not be found.",
+		},
+		{
+			Body:    `{`,
+			Headers: map[string]string{},
+			ExpCode: http.StatusBadRequest,
+			M:       "An invalid payload should be refused.",
+		},
+	}
+
+	for _, a := range x {
+		ya, fa := newHookSyncer("a", "git@example.com:org/a.git")
+		yb, fb := newHookSyncer("b", "https://example.com/org/b")
+		go ya.run()
+		go yb.run()
+
+		s := &Server{Engine: gin.New(), syncers: []*syncer{ya, yb}}
+		s.addAPI()
+
+		r := httptest.NewRequest(http.MethodPost, "/_api/hooks/git", bytes.NewBufferString(a.Body))
+		for k, v := range a.Headers {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, r)
+
+		time.Sleep(150 * time.Millisecond)
+		assert.Equal(a.ExpCode, w.Code, a.M)
+		assert.Equal(a.ExpA, atomic.LoadInt32(&fa.fetches), a.M)
+		assert.Equal(a.ExpB, atomic.LoadInt32(&fb.fetches), a.M)
+	}
+}
+
+func TestNotifyDebounce(t *testing.T) {
+	assert := assert.New(t)
+
+	y, f := newHookSyncer("a", "git@example.com:org/a.git")
+	go y.run()
+
+	for i := 0; i < 5; i++ {
+		y.notify()
+		time.Sleep(5 * time.Millisecond)
+	}
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(int32(1), atomic.LoadInt32(&f.fetches), "A burst of triggers should cause one sync.")
+
+	y.notify()
+	time.Sleep(150 * time.Millisecond)
+	assert.Equal(int32(2), atomic.LoadInt32(&f.fetches), "A later trigger should sync again.")
+}
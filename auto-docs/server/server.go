@@ -6,15 +6,17 @@ package server
 
 import (
 	"context"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"sync"
 	"syscall"
 	"time"
 
 	autodocs "github.com/cloudcloud/auto-docs"
-	"github.com/cloudcloud/auto-docs/auto-docs/data"
+	"github.com/cloudcloud/auto-docs/auto-docs/docs"
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 )
@@ -33,8 +35,19 @@ type Server struct {
 	// connections and process requests.
 	Listen string
 
+	// Docs holds the pages being served for the default branch
+	// of every source.
+	Docs *docs.Live
+
 	// Origins contains all CORS allowed origins.
 	Origins []string
+
+	// Versions holds the pages being served for each further
+	// version.
+	Versions *docs.Versions
+
+	// syncers keeps each configured source up to date.
+	syncers []*syncer
 }
 
 // New is a short-hand to give a functional method for
@@ -42,48 +55,84 @@ type Server struct {
 func New(c *autodocs.Config) *Server {
 	gin.SetMode(gin.ReleaseMode)
 
+	// allow for version names containing an escaped slash
+	e := gin.Default()
+	e.UseRawPath = true
+
 	return &Server{
-		Config:  c,
-		Engine:  gin.Default(),
-		Listen:  c.Listen,
-		Origins: []string{"*"},
+		Config:   c,
+		Docs:     docs.NewLive(),
+		Engine:   e,
+		Listen:   c.Listen,
+		Origins:  []string{"*"},
+		Versions: docs.NewVersions(),
 	}
 }
 
 // Start will finish preparing and begin serving HTTP.
 func (s *Server) Start() {
-	d := data.Prep(s.Config.Git)
-	t := time.NewTicker(
-		time.Duration(s.Config.Git.Period) * time.Second,
-	)
-	defer t.Stop()
-
-	go func(tick *time.Ticker) {
-		for {
-			select {
-			case t := <-tick.C:
-				d.Fetch(t)
-			}
+	mu := &sync.Mutex{}
+	for _, c := range s.Config.AllSources() {
+		y, err := newSyncer(c, mu, s.Docs)
+		if err != nil {
+			log.Fatalf("unable to create source %s: %s\n", c.Name, err)
 		}
-	}(t)
+
+		y.reindex = s.reindexVersions
+		s.syncers = append(s.syncers, y)
+	}
+
+	for _, y := range s.syncers {
+		go y.run()
+	}
 
 	// TODO: Allow for graceful server shutdown.
 
 	s.addMiddleware().addAPI().addHelpers().Serve()
 }
 
+// reindexVersions will rebuild the Store for each named version
+// from every source that provides it. This must be called with
+// the syncer lock held.
+func (s *Server) reindexVersions(names []string) {
+	for _, n := range names {
+		st, found := docs.NewStore(), false
+		for _, y := range s.syncers {
+			if r, ok := y.roots[n]; ok {
+				st.Mount(y.config.Mount, r, y.config.Index)
+				found = true
+			}
+		}
+
+		if found {
+			s.Versions.Set(n, st)
+		} else {
+			s.Versions.Delete(n)
+		}
+	}
+}
+
 // addAPI will add the route handling for API methods.
 func (s *Server) addAPI() *Server {
 	api := s.Engine.Group("/_api")
-	api.GET("pages", pages)
-	api.GET("page/*path", page)
+	api.GET("pages", s.pages)
+	api.GET("page/*path", s.page)
+	api.GET("history/*path", s.history)
+	api.GET("diff/*path", s.diff)
+	api.POST("hooks/git", s.hook)
+	api.GET("status", s.status)
+	api.GET("versions", s.versions)
+	api.GET("v/:ref/pages", s.versionPages)
+	api.GET("v/:ref/page/*path", s.versionPage)
 
 	return s
 }
 
 // addHelpers will add additional routes for internal working.
 func (s *Server) addHelpers() *Server {
-	s.Engine.GET("/_health", health)
+	s.Engine.GET("/_health", s.health)
+	s.Engine.GET("/_health/live", live)
+	s.Engine.GET("/_health/ready", s.ready)
 	s.Engine.NoRoute(root)
 
 	return s
@@ -105,6 +154,18 @@ func (s *Server) addMiddleware() *Server {
 	return s
 }
 
+// close will release anything held by each source, such as the lock
+// over a local path, so that a replacement may take over at once.
+func (s *Server) close() {
+	for _, y := range s.syncers {
+		if c, ok := y.source.(io.Closer); ok {
+			if err := c.Close(); err != nil {
+				log.Println("unable to close source", y.config.Name+":", err)
+			}
+		}
+	}
+}
+
 // Serve will begin to HTTP server execution.
 func (s *Server) Serve() {
 	srv := &http.Server{
@@ -119,7 +180,7 @@ func (s *Server) Serve() {
 		}
 	}()
 
-	quit := make(chan os.Signal)
+	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
@@ -129,6 +190,7 @@ func (s *Server) Serve() {
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server Shutdown:", err)
 	}
+	s.close()
 
 	select {
 	case <-ctx.Done():
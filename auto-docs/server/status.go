@@ -0,0 +1,86 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// sourceStatus is a structure to describe the sync state of a
+// single source.
+type sourceStatus struct {
+	Failures    int        `json:"failures"`
+	LastError   string     `json:"last-error,omitempty"`
+	LastErrorAt *time.Time `json:"last-error-at,omitempty"`
+	LastSuccess *time.Time `json:"last-success,omitempty"`
+	Mount       string     `json:"mount"`
+	Name        string     `json:"name"`
+	NextPoll    *time.Time `json:"next-poll,omitempty"`
+	Pages       int        `json:"pages"`
+	Ready       bool       `json:"ready"`
+	Sha         string     `json:"sha"`
+	Type        string     `json:"type"`
+	Unverified  string     `json:"unverified,omitempty"`
+}
+
+// status will describe the sync state of every source.
+func (s *Server) status(c *gin.Context) {
+	r := []sourceStatus{}
+	for _, y := range s.syncers {
+		x := y.state()
+		t := y.config.Type
+		if y.isGit() {
+			t = "git"
+		}
+
+		r = append(r, sourceStatus{
+			Failures:    x.Failures,
+			LastError:   x.LastError,
+			LastErrorAt: timeOrNil(x.LastErrorAt),
+			LastSuccess: timeOrNil(x.LastSuccess),
+			Mount:       y.config.Mount,
+			Name:        y.config.Name,
+			NextPoll:    timeOrNil(x.NextAttempt),
+			Pages:       x.Pages,
+			Ready:       x.Indexed,
+			Sha:         x.Revision,
+			Type:        t,
+			Unverified:  x.Unverified,
+		})
+	}
+
+	c.JSON(http.StatusOK, gin.H{"sources": r})
+}
+
+// live will report that the server is up and able to respond.
+func live(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// ready will report whether every source has been indexed at least
+// once, so that there is content to serve.
+func (s *Server) ready(c *gin.Context) {
+	pending := []string{}
+	for _, y := range s.syncers {
+		if !y.state().Indexed {
+			pending = append(pending, y.config.Name)
+		}
+	}
+
+	if len(pending) > 0 {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"status": "pending", "pending": pending})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": "ok"})
+}
+
+// timeOrNil gives nil for a zero time, so that it is left out.
+func timeOrNil(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+
+	return &t
+}
@@ -0,0 +1,108 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatus(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	when := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := &Server{Engine: gin.New(), syncers: []*syncer{
+		{
+			config: autodocs.Source{Mount: "/payments", Name: "payments"},
+			status: status{
+				Indexed:     true,
+				LastSuccess: when,
+				NextAttempt: when.Add(time.Minute),
+				Pages:       12,
+				Revision:    "abc123",
+				Unverified:  "def456",
+			},
+		},
+		{
+			config: autodocs.Source{Name: "platform", Type: "local"},
+			status: status{
+				Failures:    2,
+				LastError:   "unable to read directory",
+				LastErrorAt: when,
+				NextAttempt: when.Add(10 * time.Second),
+			},
+		},
+	}}
+	s.addAPI()
+
+	w := httptest.NewRecorder()
+	s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_api/status", nil))
+
+	assert.Equal(http.StatusOK, w.Code)
+	assert.JSONEq(`{"sources":[`+
+		`{"failures":0,"last-success":"2020-06-01T12:00:00Z","mount":"/payments","name":"payments",`+
+		`"next-poll":"2020-06-01T12:01:00Z","pages":12,"ready":true,"sha":"abc123","type":"git","unverified":"def456"},`+
+		`{"failures":2,"last-error":"unable to read directory","last-error-at":"2020-06-01T12:00:00Z",`+
+		`"mount":"","name":"platform","next-poll":"2020-06-01T12:00:10Z","pages":0,"ready":false,"sha":"","type":"local"}]}`,
+		w.Body.String(), "Each source should be described.")
+}
+
+type readyStruct struct {
+	Indexed []bool
+	Path    string
+	ExpCode int
+	ExpBody string
+	M       string
+}
+
+func TestReady(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	x := []readyStruct{
+		{
+			Indexed: []bool{true, false},
+			Path:    "/_health/ready",
+			ExpCode: http.StatusServiceUnavailable,
+			ExpBody: `{"status":"pending","pending":["source-1"]}`,
+			M:       "Readiness should fail until every source is indexed.",
+		},
+		{
+			Indexed: []bool{true, true},
+			Path:    "/_health/ready",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"status":"ok"}`,
+			M:       "Readiness should pass once every source is indexed.",
+		},
+		{
+			Indexed: []bool{false, false},
+			Path:    "/_health/live",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"status":"ok"}`,
+			M:       "Liveness should pass before any source is indexed.",
+		},
+	}
+
+	for _, a := range x {
+		s := &Server{Engine: gin.New()}
+		for i, v := range a.Indexed {
+			s.syncers = append(s.syncers, &syncer{
+				config: autodocs.Source{Name: "source-" + fmt.Sprint(i)},
+				status: status{Indexed: v},
+			})
+		}
+		s.addHelpers()
+
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, a.Path, nil))
+
+		assert.Equal(a.ExpCode, w.Code, a.M)
+		assert.JSONEq(a.ExpBody, w.Body.String(), a.M)
+	}
+}
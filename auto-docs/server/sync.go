@@ -0,0 +1,428 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/cloudcloud/auto-docs/auto-docs/data"
+	"github.com/cloudcloud/auto-docs/auto-docs/docs"
+	"gopkg.in/src-d/go-billy.v4"
+)
+
+// syncer keeps the pages from a single source up to date within
+// the docs store.
+type syncer struct {
+	// config contains the configuration for the source.
+	config autodocs.Source
+
+	// status records the outcome of recent attempts to sync,
+	// guarded by sm.
+	status status
+
+	// sm guards status, which is read while syncing.
+	sm sync.RWMutex
+
+	// source is the backend content is loaded from.
+	source data.Source
+
+	// mu is shared between syncers to serialise version updates.
+	mu *sync.Mutex
+
+	// live holds the pages being served, which the source is
+	// loaded into.
+	live *docs.Live
+
+	// trigger receives requests to sync outside of the period.
+	trigger chan struct{}
+
+	// delay is how long to wait after a trigger before syncing,
+	// allowing a burst of triggers to cause a single sync.
+	delay time.Duration
+
+	// retry is how long to wait after the first failure to sync,
+	// doubling with each failure in a row up to retryLimit.
+	retry, retryLimit time.Duration
+
+	// versions holds each further version of the source, guarded
+	// by mu.
+	versions []autodocs.Version
+
+	// roots holds the filesystem content for each version is read
+	// from, guarded by mu.
+	roots map[string]billy.Filesystem
+
+	// reindex is called with mu held whenever versions of the
+	// source are added, removed or changed.
+	reindex func(names []string)
+
+	// info holds the latest commit for each file as of the last
+	// update, so that only changed files need to be looked up.
+	info map[string]autodocs.Commit
+}
+
+// status is a structure to describe the outcome of recent attempts
+// to sync a source.
+type status struct {
+	// Failures counts the attempts that have failed in a row.
+	Failures int
+
+	// LastError describes the most recent failure, kept until a
+	// later attempt succeeds.
+	LastError string
+
+	// LastErrorAt is when the most recent failure happened.
+	LastErrorAt time.Time
+
+	// LastSuccess is when an attempt last succeeded.
+	LastSuccess time.Time
+
+	// NextAttempt is when the source will next be checked.
+	NextAttempt time.Time
+
+	// Pages counts the pages loaded from the source when it was
+	// last indexed.
+	Pages int
+
+	// Indexed is set once the source has been loaded into the
+	// store for the first time.
+	Indexed bool
+
+	// Revision identifies the content as of the last success.
+	Revision string
+
+	// Unverified is the latest revision refused for not being
+	// signed by a trusted key, while the last signed one is kept.
+	Unverified string
+}
+
+// triggerDelay is the default time to wait for further triggers
+// before syncing out-of-band.
+const triggerDelay = 2 * time.Second
+
+// retryBase and retryMax are the default bounds on the time to
+// wait before retrying a source that is failing to sync.
+const (
+	retryBase = 5 * time.Second
+	retryMax  = 10 * time.Minute
+)
+
+// newSyncer will create the source described by the provided
+// configuration, to be loaded into the live pages.
+func newSyncer(c autodocs.Source, mu *sync.Mutex, l *docs.Live) (*syncer, error) {
+	d, err := data.New(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return &syncer{
+		config:     c,
+		source:     d,
+		mu:         mu,
+		live:       l,
+		trigger:    make(chan struct{}, 1),
+		delay:      triggerDelay,
+		retry:      retryBase,
+		retryLimit: retryMax,
+		roots:      map[string]billy.Filesystem{},
+	}, nil
+}
+
+// prepare will ready the source and load the initial pages. A
+// failure is recorded and left to be retried.
+func (y *syncer) prepare() error {
+	err := guard(y.source.Prepare)
+	if err != nil {
+		log.Println("unable to prepare source", y.config.Name+":", err)
+		return err
+	}
+
+	y.update()
+	y.updateVersions()
+	return nil
+}
+
+// run will prepare the source, then poll it every period as well
+// as whenever a sync has been triggered, updating the store when
+// the source reports a change. While the source is failing, it is
+// retried with backoff instead, and the last good content is kept.
+func (y *syncer) run() {
+	t := time.NewTimer(y.record(time.Now(), y.prepare()))
+	defer t.Stop()
+
+	var debounce <-chan time.Time
+	for {
+		select {
+		case now := <-t.C:
+			t.Reset(y.record(now, y.fetch(now)))
+
+		case <-y.trigger:
+			if debounce == nil {
+				debounce = time.After(y.delay)
+			}
+
+		case now := <-debounce:
+			debounce = nil
+			wait := y.record(now, y.fetch(now))
+			if !t.Stop() {
+				select {
+				case <-t.C:
+				default:
+				}
+			}
+			t.Reset(wait)
+		}
+	}
+}
+
+// record will note the outcome of an attempt to sync, giving how
+// long to wait before the next attempt.
+func (y *syncer) record(now time.Time, err error) time.Duration {
+	y.sm.Lock()
+	defer y.sm.Unlock()
+
+	wait := y.period()
+	if v, ok := y.source.(data.Verified); ok {
+		y.status.Unverified = v.Unverified()
+	}
+	if err == nil {
+		y.status.Failures = 0
+		y.status.LastError = ""
+		y.status.LastSuccess = now
+		y.status.Revision = y.source.Revision()
+	} else {
+		y.status.Failures++
+		y.status.LastError = err.Error()
+		y.status.LastErrorAt = now
+		wait = backoff(y.status.Failures, y.retry, y.retryLimit)
+
+		log.Println("retrying source", y.config.Name, "in", wait.Round(time.Second))
+	}
+	y.status.NextAttempt = now.Add(wait)
+
+	return wait
+}
+
+// state gives the outcome of recent attempts to sync.
+func (y *syncer) state() status {
+	y.sm.RLock()
+	defer y.sm.RUnlock()
+
+	return y.status
+}
+
+// notify will request an out-of-band sync without blocking. When
+// one is already pending, the request is merged into it.
+func (y *syncer) notify() {
+	select {
+	case y.trigger <- struct{}{}:
+	default:
+	}
+}
+
+// fetch will check the source for changes, updating the store
+// when any are found, the source has yet to be indexed, or it has
+// moved on since the last success without reporting a change, as
+// after a fetch that failed part way through. On failure the store
+// is left untouched.
+func (y *syncer) fetch(now time.Time) error {
+	var changed bool
+	err := guard(func() (err error) {
+		changed, err = y.source.Fetch(now)
+		return err
+	})
+	if err != nil {
+		log.Println("unable to fetch source", y.config.Name+":", err)
+		return err
+	}
+
+	if st := y.state(); changed || !st.Indexed || y.source.Revision() != st.Revision {
+		// tell data to re-process
+		log.Println("updating", y.config.Name, "from source at", now)
+		y.update()
+	}
+	y.updateVersions()
+
+	return nil
+}
+
+// update will load the content of the source into a new copy of
+// the store, which replaces the one being served once complete.
+// Where possible, only the files changed since the last sync are
+// loaded again.
+func (y *syncer) update() {
+	changes := y.changes()
+	info := y.lastModified(changes)
+
+	n := 0
+	y.live.Update(func(st *docs.Store) {
+		ok := false
+		if changes != nil {
+			n, ok = st.Patch(y.config.Mount, y.source.FS(), changes)
+		}
+		if !ok {
+			st.Redirect(y.config.Mount, changes)
+			n = st.Mount(y.config.Mount, y.source.FS(), y.config.Index)
+		}
+		st.Annotate(y.config.Mount, info)
+	})
+
+	y.sm.Lock()
+	defer y.sm.Unlock()
+
+	y.status.Pages = n
+	y.status.Indexed = true
+}
+
+// lastModified gives the latest commit for each file within the
+// source, when history is available. With the changes since the last
+// update, only the files changed are looked up, and the rest are kept
+// from before.
+func (y *syncer) lastModified(changes []autodocs.Change) map[string]autodocs.Commit {
+	h, ok := y.source.(data.Historic)
+	if !ok {
+		return nil
+	}
+
+	var files []string
+	if changes != nil && y.info != nil {
+		files = []string{}
+		for _, x := range changes {
+			if x.To != "" {
+				files = append(files, x.To)
+			}
+		}
+	}
+
+	info, err := h.LastModified(y.source.Revision(), files)
+	if err != nil {
+		if err != data.ErrNoHistory {
+			log.Println("unable to load history of", y.config.Name+":", err)
+		}
+
+		y.info = nil
+		return info
+	}
+
+	if files != nil {
+		r := map[string]autodocs.Commit{}
+		for k, v := range y.info {
+			r[k] = v
+		}
+		for _, x := range changes {
+			delete(r, x.From)
+		}
+		for k, v := range info {
+			r[k] = v
+		}
+		info = r
+	}
+
+	y.info = info
+	return info
+}
+
+// changes gives the files changed since the revision last synced,
+// when the source is able to compare revisions. Without these, the
+// source is indexed in full. Submodules are not compared, so their
+// content is always indexed in full.
+func (y *syncer) changes() []autodocs.Change {
+	h, ok := y.source.(data.Historic)
+	prev, cur := y.state().Revision, y.source.Revision()
+	if !ok || prev == "" || prev == cur || y.config.Git.Submodules {
+		return nil
+	}
+
+	changes, err := h.Changes(prev, cur)
+	if err != nil {
+		log.Println("unable to compare revisions of", y.config.Name+":", err)
+		return nil
+	}
+
+	return changes
+}
+
+// updateVersions will locate the content for each version of
+// the source, requesting a reindex of any that have changed.
+func (y *syncer) updateVersions() {
+	v, ok := y.source.(data.Versioned)
+	if !ok {
+		return
+	}
+
+	versions, roots := v.Versions(), map[string]billy.Filesystem{}
+	for _, x := range versions {
+		r, err := v.VersionFS(x)
+		if err != nil {
+			log.Println("unable to load version", x.Name, "of", y.config.Name+":", err)
+			continue
+		}
+
+		roots[x.Name] = r
+	}
+
+	y.mu.Lock()
+	defer y.mu.Unlock()
+
+	changed := []string{}
+	for n, r := range roots {
+		if y.roots[n] != r {
+			changed = append(changed, n)
+		}
+	}
+	for n := range y.roots {
+		if _, ok := roots[n]; !ok {
+			changed = append(changed, n)
+		}
+	}
+
+	y.versions, y.roots = versions, roots
+	if len(changed) > 0 && y.reindex != nil {
+		y.reindex(changed)
+	}
+}
+
+// guard will run the operation, turning any panic into an error so
+// that a single source can't bring down the server.
+func guard(op func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+
+	return op()
+}
+
+// backoff gives the wait before the next attempt after a number of
+// failures in a row, doubling from the base up to the limit. Jitter
+// keeps sources that failed together from retrying together.
+func backoff(failures int, base, limit time.Duration) time.Duration {
+	d := base
+	for i := 1; i < failures && d < limit; i++ {
+		d *= 2
+	}
+	if d > limit {
+		d = limit
+	}
+
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// isGit checks whether the source is a git repository.
+func (y *syncer) isGit() bool {
+	return y.config.Type == "" || y.config.Type == "git"
+}
+
+// period gives the polling interval for the source.
+func (y *syncer) period() time.Duration {
+	p := y.config.Git.Period
+	if !y.isGit() {
+		p = y.config.Local.Period
+	}
+
+	return time.Duration(p) * time.Second
+}
@@ -0,0 +1,206 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/cloudcloud/auto-docs/auto-docs/docs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/memfs"
+	"gopkg.in/src-d/go-billy.v4/util"
+)
+
+// flakySource is a data.Source that fails a number of times before
+// it succeeds.
+type flakySource struct {
+	fakeSource
+
+	failures int32
+
+	panics bool
+}
+
+func (f *flakySource) Prepare() error {
+	return f.fail()
+}
+func (f *flakySource) Fetch(t time.Time) (bool, error) {
+	atomic.AddInt32(&f.fetches, 1)
+	return false, f.fail()
+}
+func (f *flakySource) fail() error {
+	if atomic.AddInt32(&f.failures, -1) < 0 {
+		return nil
+	}
+	if f.panics {
+		panic("nil pointer")
+	}
+	return fmt.Errorf("unable to reach remote")
+}
+
+// unsignedSource is a data.Source that refuses a revision for not
+// being signed by a trusted key.
+type unsignedSource struct {
+	fakeSource
+
+	unverified string
+}
+
+func (u *unsignedSource) Unverified() string { return u.unverified }
+
+// movedSource is a data.Source whose revision moves on without a
+// fetch reporting a change.
+type movedSource struct {
+	fakeSource
+
+	fs billy.Filesystem
+
+	rev string
+}
+
+func (m *movedSource) Revision() string     { return m.rev }
+func (m *movedSource) FS() billy.Filesystem { return m.fs }
+
+type backoffStruct struct {
+	Failures int
+	Min      time.Duration
+	Max      time.Duration
+	M        string
+}
+
+func TestBackoff(t *testing.T) {
+	assert := assert.New(t)
+	x := []backoffStruct{
+		{Failures: 1, Min: 5 * time.Second, Max: 10 * time.Second, M: "The first retry should be near the base."},
+		{Failures: 2, Min: 10 * time.Second, Max: 20 * time.Second, M: "The wait should double."},
+		{Failures: 4, Min: 40 * time.Second, Max: 80 * time.Second, M: "The wait should keep doubling."},
+		{Failures: 50, Min: 5 * time.Minute, Max: 10 * time.Minute, M: "The wait should not pass the limit."},
+	}
+
+	for _, a := range x {
+		for i := 0; i < 20; i++ {
+			d := backoff(a.Failures, 10*time.Second, 10*time.Minute)
+			assert.True(d >= a.Min && d <= a.Max, a.M, d)
+		}
+	}
+}
+
+type retryStruct struct {
+	Source *flakySource
+	M      string
+}
+
+func TestRunRetry(t *testing.T) {
+	assert := assert.New(t)
+	x := []retryStruct{
+		{Source: &flakySource{failures: 3}, M: "Errors should be retried until the source recovers."},
+		{Source: &flakySource{failures: 3, panics: true}, M: "Panics should be retried until the source recovers."},
+	}
+
+	for _, a := range x {
+		y := &syncer{
+			config:     autodocs.Source{Git: autodocs.Git{Period: 3600}, Name: "flaky"},
+			source:     a.Source,
+			mu:         &sync.Mutex{},
+			live:       docs.NewLive(),
+			trigger:    make(chan struct{}, 1),
+			retry:      10 * time.Millisecond,
+			retryLimit: 20 * time.Millisecond,
+		}
+		go y.run()
+
+		time.Sleep(10 * time.Millisecond)
+		st := y.state()
+		assert.True(st.Failures > 0, a.M)
+		assert.NotEmpty(st.LastError, a.M)
+		assert.True(st.LastSuccess.IsZero(), a.M)
+
+		time.Sleep(150 * time.Millisecond)
+		st = y.state()
+		assert.Equal(0, st.Failures, a.M)
+		assert.Empty(st.LastError, a.M)
+		assert.False(st.LastSuccess.IsZero(), a.M)
+		assert.True(st.Indexed, "Recovering should index the source.")
+		assert.Equal(int32(3), atomic.LoadInt32(&a.Source.fetches), a.M)
+		assert.True(st.NextAttempt.Sub(st.LastSuccess) == time.Hour, "After recovering the period should be used.")
+	}
+}
+
+func TestFetchMoved(t *testing.T) {
+	assert := assert.New(t)
+	fs := memfs.New()
+	assert.Nil(util.WriteFile(fs, "readme.md", []byte("# readme"), 0644))
+
+	m := &movedSource{fs: fs, rev: "abc123"}
+	y := &syncer{config: autodocs.Source{Name: "moved"}, source: m, mu: &sync.Mutex{}, live: docs.NewLive()}
+
+	now := time.Now()
+	y.record(now, y.fetch(now))
+	assert.Equal(1, y.state().Pages, "A source not yet indexed should be indexed.")
+
+	assert.Nil(util.WriteFile(fs, "other.md", []byte("# other"), 0644))
+	y.record(now, y.fetch(now))
+	assert.Equal(1, y.state().Pages, "An unchanged source should not be indexed again.")
+
+	m.rev = "def456"
+	y.record(now, y.fetch(now))
+	assert.Equal(2, y.state().Pages, "A source that moved on without a change should be indexed again.")
+	assert.Equal("def456", y.state().Revision)
+}
+
+func TestRecordUnverified(t *testing.T) {
+	assert := assert.New(t)
+	u := &unsignedSource{unverified: "def456"}
+	y := &syncer{config: autodocs.Source{Git: autodocs.Git{Period: 60}}, source: u}
+
+	y.record(time.Now(), nil)
+	assert.Equal("def456", y.state().Unverified, "A refused revision should be reported.")
+
+	u.unverified = ""
+	y.record(time.Now(), nil)
+	assert.Empty(y.state().Unverified, "A signed revision should clear the report.")
+}
+
+type healthStruct struct {
+	Status  status
+	ExpBody string
+	M       string
+}
+
+func TestHealth(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	x := []healthStruct{
+		{
+			Status:  status{LastSuccess: time.Now()},
+			ExpBody: `{"status":"ok"}`,
+			M:       "Sources syncing should be ok.",
+		},
+		{
+			Status:  status{Failures: 2, LastError: "unable to reach remote"},
+			ExpBody: `{"status":"degraded","errors":{"flaky":"unable to reach remote"}}`,
+			M:       "Sources failing to sync should be reported.",
+		},
+	}
+
+	for _, a := range x {
+		s := &Server{Engine: gin.New(), syncers: []*syncer{
+			{config: autodocs.Source{Name: "flaky"}, status: a.Status},
+		}}
+		s.addHelpers()
+
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_health", nil))
+
+		assert.Equal(http.StatusOK, w.Code, a.M)
+		assert.JSONEq(a.ExpBody, w.Body.String(), a.M)
+	}
+}
@@ -0,0 +1,78 @@
+package server
+
+import (
+	"net/http"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/cloudcloud/auto-docs/auto-docs/docs"
+	"github.com/gin-gonic/gin"
+)
+
+// versions will list the default and further versions available
+// across every source.
+func (s *Server) versions(c *gin.Context) {
+	l, seen := []autodocs.Version{}, map[string]bool{}
+	add := func(v autodocs.Version) {
+		if !seen[v.Name] {
+			seen[v.Name] = true
+			l = append(l, v)
+		}
+	}
+
+	for _, y := range s.syncers {
+		if y.isGit() {
+			add(autodocs.Version{Default: true, Kind: "branch", Name: y.config.Git.Branch})
+		}
+	}
+	for _, y := range s.syncers {
+		y.mu.Lock()
+		for _, v := range y.versions {
+			add(v)
+		}
+		y.mu.Unlock()
+	}
+
+	c.JSON(http.StatusOK, gin.H{"versions": l})
+}
+
+// versionPages will provide the tree structure of pages for a
+// single version.
+func (s *Server) versionPages(c *gin.Context) {
+	if st, ok := s.store(c.Param("ref")); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+	} else {
+		c.JSON(http.StatusOK, st)
+	}
+}
+
+// versionPage will retrieve the data for a single page within a
+// single version.
+func (s *Server) versionPage(c *gin.Context) {
+	st, ok := s.store(c.Param("ref"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+
+	if p, ok := st.Page(c.Param("path")); !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Path not found"})
+	} else {
+		c.JSON(http.StatusOK, p)
+	}
+}
+
+// store will find the Store for a version, where the configured
+// branch of any source is served from the default Store.
+func (s *Server) store(ref string) (*docs.Store, bool) {
+	if st, ok := s.Versions.Get(ref); ok {
+		return st, true
+	}
+
+	for _, y := range s.syncers {
+		if y.isGit() && y.config.Git.Branch == ref {
+			return s.Docs.Load(), true
+		}
+	}
+
+	return nil, false
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	autodocs "github.com/cloudcloud/auto-docs"
+	"github.com/cloudcloud/auto-docs/auto-docs/docs"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/src-d/go-billy.v4"
+	"gopkg.in/src-d/go-billy.v4/osfs"
+)
+
+type versionStruct struct {
+	Path    string
+	ExpCode int
+	ExpBody string
+	M       string
+}
+
+func TestVersionsAPI(t *testing.T) {
+	assert := assert.New(t)
+	gin.SetMode(gin.TestMode)
+
+	wd, _ := os.Getwd()
+	testdata := filepath.Join(wd, "..", "docs", "testdata")
+
+	mu := &sync.Mutex{}
+	a := &syncer{
+		config: autodocs.Source{Git: autodocs.Git{Branch: "master"}, Mount: "/a"},
+		mu:     mu,
+		roots:  map[string]billy.Filesystem{"v1.0": osfs.New(testdata)},
+		versions: []autodocs.Version{
+			{Kind: "tag", Name: "v1.0"},
+		},
+	}
+	b := &syncer{
+		config: autodocs.Source{Git: autodocs.Git{Branch: "main"}, Mount: "/b"},
+		mu:     mu,
+		roots:  map[string]billy.Filesystem{"v1.0": osfs.New(filepath.Join(testdata, "first")), "release/2": osfs.New(testdata)},
+		versions: []autodocs.Version{
+			{Kind: "branch", Name: "release/2"},
+			{Kind: "tag", Name: "v1.0"},
+		},
+	}
+
+	s := &Server{Docs: docs.NewLive(), Engine: gin.New(), Versions: docs.NewVersions(), syncers: []*syncer{a, b}}
+	s.Engine.UseRawPath = true
+	s.addAPI()
+	s.reindexVersions([]string{"v1.0", "release/2", "gone"})
+
+	x := []versionStruct{
+		{
+			Path:    "/_api/versions",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"versions":[` +
+				`{"default":true,"kind":"branch","name":"master"},` +
+				`{"default":true,"kind":"branch","name":"main"},` +
+				`{"default":false,"kind":"tag","name":"v1.0"},` +
+				`{"default":false,"kind":"branch","name":"release/2"}]}`,
+			M: "Versions should be listed once each, defaults first.",
+		},
+		{
+			Path:    "/_api/v/v1.0/page/a/root",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"name":"root","content":"<h1>root</h1>\n","title":"root"}`,
+			M:       "A page should be served from its version.",
+		},
+		{
+			Path:    "/_api/v/v1.0/page/b/one",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"name":"one","content":"<h1>one</h1>\n","title":"one"}`,
+			M:       "A version should combine every source.",
+		},
+		{
+			Path:    "/_api/v/release%2F2/page/b/root",
+			ExpCode: http.StatusOK,
+			ExpBody: `{"name":"root","content":"<h1>root</h1>\n","title":"root"}`,
+			M:       "An escaped slash should be allowed in the version.",
+		},
+		{
+			Path:    "/_api/v/release%2F2/page/a/root",
+			ExpCode: http.StatusNotFound,
+			ExpBody: `{"error":"Path not found"}`,
+			M:       "A source without the version should not be served.",
+		},
+		{
+			Path:    "/_api/v/gone/pages",
+			ExpCode: http.StatusNotFound,
+			ExpBody: `{"error":"Version not found"}`,
+			M:       "An unknown version should not be found.",
+		},
+	}
+
+	for _, a := range x {
+		w := httptest.NewRecorder()
+		s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, a.Path, nil))
+
+		assert.Equal(a.ExpCode, w.Code, a.M)
+		assert.JSONEq(a.ExpBody, w.Body.String(), a.M)
+	}
+
+	st, ok := s.store("main")
+	assert.True(ok, "A default branch should be found.")
+	assert.Equal(s.Docs.Load(), st, "A default branch should be served from the default store.")
+
+	w := httptest.NewRecorder()
+	s.Engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/_api/v/v1.0/pages", nil))
+	p := map[string]interface{}{}
+	assert.Nil(json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Len(p["pages"], 2, "Each source should be mounted within the version.")
+}
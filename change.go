@@ -0,0 +1,24 @@
+package autodocs
+
+// Kinds of Change that can be made to a file between revisions.
+const (
+	ChangeAdded    = "added"
+	ChangeModified = "modified"
+	ChangeRemoved  = "removed"
+	ChangeRenamed  = "renamed"
+)
+
+// Change is a structure to describe how a single file differs
+// between two revisions.
+type Change struct {
+	// Kind is one of added, modified, removed or renamed.
+	Kind string `json:"kind"`
+
+	// From is the location of the file at the earlier revision,
+	// empty when it has been added.
+	From string `json:"from,omitempty"`
+
+	// To is the location of the file at the later revision, empty
+	// when it has been removed.
+	To string `json:"to,omitempty"`
+}
@@ -0,0 +1,26 @@
+package autodocs
+
+import "time"
+
+// Commit is a structure to describe a single change made to the
+// docs within a source.
+type Commit struct {
+	// Author is the name of the person who made the change.
+	Author string `json:"author"`
+
+	// Date is when the change was made.
+	Date time.Time `json:"date"`
+
+	// Email is the address of the person who made the change.
+	Email string `json:"email"`
+
+	// Message describes the change.
+	Message string `json:"message"`
+
+	// Path is the location of the file as of this change, which
+	// differs from the current location when it has been renamed.
+	Path string `json:"path"`
+
+	// Sha identifies the change.
+	Sha string `json:"sha"`
+}
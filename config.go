@@ -1,16 +1,98 @@
 package autodocs
 
+import (
+	"fmt"
+	"path/filepath"
+)
+
 // Config manages all configuration attributes for auto-docs.
 type Config struct {
 	// Git captures details about working with git.
 	Git Git
 
+	// Index captures which files within the source are served.
+	Index Index
+
 	// Listen contains the host:port for listening on HTTP
 	// requests incoming.
 	Listen string
 
+	// Local captures details about working with a directory
+	// already present on disk.
+	Local Local
+
 	// Name is the identifier and brand for auto-docs.
 	Name string
+
+	// Source selects the kind of backend that documentation is
+	// loaded from, either "git" or "local". If not specified,
+	// git is used.
+	Source string
+
+	// Sources lists each backend documentation is loaded from,
+	// with every one mounted under its own path prefix. When
+	// empty, a single source is built from Source, Git and Local.
+	Sources []Source
+}
+
+// Source is a structure to capture a single backend that will be
+// merged into the docs tree.
+type Source struct {
+	// Git captures details about working with git.
+	Git Git
+
+	// Index captures which files within the source are served.
+	Index Index
+
+	// Local captures details about working with a directory
+	// already present on disk.
+	Local Local
+
+	// Mount is the path prefix that pages from this source are
+	// served under. If not specified, pages are served from the
+	// root.
+	Mount string
+
+	// Name is the identifier for this source.
+	Name string
+
+	// Type selects the kind of backend, either "git" or "local".
+	// If not specified, the top-level Source is used.
+	Type string
+}
+
+// AllSources will provide every configured Source, falling back
+// to the top-level Source, Git and Local details when no list of
+// Sources has been provided.
+func (c *Config) AllSources() []Source {
+	if len(c.Sources) == 0 {
+		return []Source{
+			{
+				Git:   c.Git,
+				Index: c.Index,
+				Local: c.Local,
+				Name:  "default",
+				Type:  c.Source,
+			},
+		}
+	}
+
+	s := make([]Source, len(c.Sources))
+	for i, x := range c.Sources {
+		if x.Name == "" {
+			x.Name = fmt.Sprintf("source-%d", i)
+		}
+		x.Git = x.Git.withDefaults(c.Git, x.Name)
+		x.Index = x.Index.withDefaults(c.Index)
+		x.Local = x.Local.withDefaults(c.Local)
+		if x.Type == "" {
+			x.Type = c.Source
+		}
+
+		s[i] = x
+	}
+
+	return s
 }
 
 // Git is a structure to capture information about working with
@@ -20,24 +102,92 @@ type Git struct {
 	// If not specified, master is used.
 	Branch string
 
+	// Branches lists further branch names or patterns, such as
+	// release/*, to be served as separate versions of the docs.
+	Branches []string
+
+	// CloneTimeout specifies length of time, in milliseconds, to
+	// wait before terminating a clone of the repository or of its
+	// submodules. Defaults to Timeout.
+	CloneTimeout int
+
+	// History fetches the full history of the repository rather
+	// than only the latest commit, allowing pages to be loaded as
+	// they were at any past revision.
+	History bool
+
+	// HostKeys pins the SSH host keys of the remote by their
+	// SHA256 fingerprint, as given by ssh-keygen -lf. When set,
+	// the host must present one of these keys, or one listed in
+	// KnownHosts.
+	HostKeys []string
+
+	// InsecureIgnoreHostKey disables verification of the SSH host
+	// key of the remote. This is open to man-in-the-middle attacks
+	// and should only be used for testing.
+	InsecureIgnoreHostKey bool
+
+	// Keyring is the path to a file of armored public keys, as
+	// given by gpg --export --armor. When set, only commits signed
+	// by one of these keys are checked out, and the last signed
+	// commit is kept in place of any that are not.
+	Keyring string
+
+	// KnownHosts is the path to a known_hosts file used to verify
+	// the SSH host key of the remote. If not specified, and there
+	// are no HostKeys, SSH_KNOWN_HOSTS, ~/.ssh/known_hosts and
+	// /etc/ssh/ssh_known_hosts are used.
+	KnownHosts string
+
 	// LocalPath contains a local location that is used to store
 	// and interact with the repository.
 	LocalPath string
 
-	// Password is used for the username authentication.
+	// Memory holds the repository and its checkout in memory
+	// rather than within LocalPath, so that no writable disk is
+	// needed.
+	Memory bool
+
+	// Password is used for the username authentication with
+	// HTTP(S) remotes, or as the passphrase for the SSHKey.
 	Password string
 
+	// Secret is shared with the git host to verify push webhooks.
+	// If not specified, webhooks are not accepted.
+	Secret string
+
+	// SSHAgent authenticates SSH remotes using the agent found
+	// at SSH_AUTH_SOCK, rather than the SSHKey.
+	SSHAgent bool
+
 	// SSHKey captures a key for use with SSH authentication.
 	SSHKey string
 
-	// Timeout specifies length of time to wait before terminating
-	// checking for an update.
+	// Submodules checks out each submodule of the repository,
+	// recursively, so that their content is served along with
+	// the rest of the repository.
+	Submodules bool
+
+	// Tags lists tag names or patterns, such as v*, to be served
+	// as separate versions of the docs.
+	Tags []string
+
+	// Token is used for authentication with HTTP(S) remotes in
+	// place of a password, such as a deploy or access token.
+	Token string
+
+	// Timeout specifies length of time, in milliseconds, to wait
+	// before terminating a check for an update. It also bounds the
+	// wait for each response from HTTP(S) remotes.
 	Timeout int
 
 	// URI for the repository remote.
 	URI string
 
-	// Username captures the user to connect as.
+	// Username captures the user to connect as. For SSH remotes
+	// the user within the URI is used when not specified, and for
+	// HTTP(S) remotes with no Username, Password or Token, access
+	// is anonymous.
 	Username string
 
 	// Period provides a way to specify the number of seconds
@@ -45,4 +195,105 @@ type Git struct {
 	Period int
 }
 
-//
+// withDefaults will fill in any unset attributes from the base
+// configuration, giving each source its own local path. Flags are
+// not filled in, as a source would have no way to turn them off.
+func (g Git) withDefaults(b Git, name string) Git {
+	if g.Branch == "" {
+		g.Branch = b.Branch
+	}
+	if g.Branches == nil {
+		g.Branches = b.Branches
+	}
+	if g.HostKeys == nil {
+		g.HostKeys = b.HostKeys
+	}
+	if g.Keyring == "" {
+		g.Keyring = b.Keyring
+	}
+	if g.KnownHosts == "" {
+		g.KnownHosts = b.KnownHosts
+	}
+	if g.LocalPath == "" {
+		g.LocalPath = filepath.Join(b.LocalPath, name)
+	}
+	if g.Secret == "" {
+		g.Secret = b.Secret
+	}
+	if g.SSHKey == "" {
+		g.SSHKey = b.SSHKey
+	}
+	if g.Tags == nil {
+		g.Tags = b.Tags
+	}
+	if g.Timeout == 0 {
+		g.Timeout = b.Timeout
+	}
+	if g.CloneTimeout == 0 {
+		g.CloneTimeout = b.CloneTimeout
+	}
+	if g.Period == 0 {
+		g.Period = b.Period
+	}
+
+	return g
+}
+
+// Index is a structure to capture which markdown files within a
+// source are served as pages. Any .autodocsignore file within the
+// source is also applied, in the same way as a .gitignore file.
+type Index struct {
+	// Exclude lists patterns, in the style of .gitignore and
+	// relative to the Root, for files that should not be served.
+	Exclude []string
+
+	// Include lists patterns, in the style of .gitignore and
+	// relative to the Root, for the only files to be served. If
+	// not specified, every markdown file is served.
+	Include []string
+
+	// Root is the directory within the source that pages are
+	// served from, such as docs. If not specified, the whole
+	// source is used.
+	Root string
+}
+
+// withDefaults will fill in any unset attributes from the base
+// configuration.
+func (i Index) withDefaults(b Index) Index {
+	if i.Exclude == nil {
+		i.Exclude = b.Exclude
+	}
+	if i.Include == nil {
+		i.Include = b.Include
+	}
+	if i.Root == "" {
+		i.Root = b.Root
+	}
+
+	return i
+}
+
+// Local is a structure to capture information about working with
+// a plain directory, such as a mounted volume.
+type Local struct {
+	// Path is the directory that documentation is read from.
+	Path string
+
+	// Period provides a way to specify the number of seconds
+	// between checking the directory for changes.
+	Period int
+}
+
+// withDefaults will fill in any unset attributes from the base
+// configuration.
+func (l Local) withDefaults(b Local) Local {
+	if l.Path == "" {
+		l.Path = b.Path
+	}
+	if l.Period == 0 {
+		l.Period = b.Period
+	}
+
+	return l
+}
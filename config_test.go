@@ -0,0 +1,85 @@
+package autodocs
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sourcesStruct struct {
+	Config *Config
+	Exp    []Source
+	M      string
+}
+
+func TestAllSources(t *testing.T) {
+	assert := assert.New(t)
+	base := Git{Branch: "master", CloneTimeout: 600000, LocalPath: "/tmp/auto-docs", Period: 300, Timeout: 30000}
+	x := []sourcesStruct{
+		{
+			Config: &Config{Git: base, Source: "git"},
+			Exp:    []Source{{Git: base, Name: "default", Type: "git"}},
+			M:      "No list of sources should fall back to the top level.",
+		},
+		{
+			Config: &Config{
+				Git:    base,
+				Index:  Index{Exclude: []string{"vendor/"}, Root: "docs"},
+				Local:  Local{Path: "/srv/docs", Period: 60},
+				Source: "git",
+				Sources: []Source{
+					{
+						Git:   Git{URI: "git@example.com:a/pay.git", Branch: "main"},
+						Index: Index{Root: "site"},
+						Mount: "/payments",
+						Name:  "payments",
+					},
+					{Type: "local", Local: Local{Path: "/docs"}, Mount: "/platform"},
+				},
+			},
+			Exp: []Source{
+				{
+					Git: Git{
+						URI:          "git@example.com:a/pay.git",
+						Branch:       "main",
+						CloneTimeout: 600000,
+						LocalPath:    "/tmp/auto-docs/payments",
+						Period:       300,
+						Timeout:      30000,
+					},
+					Index: Index{Exclude: []string{"vendor/"}, Root: "site"},
+					Local: Local{Path: "/srv/docs", Period: 60},
+					Mount: "/payments",
+					Name:  "payments",
+					Type:  "git",
+				},
+				{
+					Git:   Git{Branch: "master", CloneTimeout: 600000, LocalPath: "/tmp/auto-docs/source-1", Period: 300, Timeout: 30000},
+					Index: Index{Exclude: []string{"vendor/"}, Root: "docs"},
+					Local: Local{Path: "/docs", Period: 60},
+					Mount: "/platform",
+					Name:  "source-1",
+					Type:  "local",
+				},
+			},
+			M: "Each source should be filled from the top level.",
+		},
+		{
+			Config: &Config{Local: Local{Path: "/srv/docs"}, Source: "local", Sources: []Source{{Name: "docs"}}},
+			Exp:    []Source{{Git: Git{LocalPath: "docs"}, Local: Local{Path: "/srv/docs"}, Name: "docs", Type: "local"}},
+			M:      "The kind of source and its path should be filled from the top level.",
+		},
+		{
+			Config: &Config{
+				Git:     Git{History: true, InsecureIgnoreHostKey: true, Memory: true, SSHAgent: true, Submodules: true},
+				Sources: []Source{{Name: "docs"}},
+			},
+			Exp: []Source{{Git: Git{LocalPath: "docs"}, Name: "docs"}},
+			M:   "Flags should not be filled from the top level.",
+		},
+	}
+
+	for _, a := range x {
+		assert.Equal(a.Exp, a.Config.AllSources(), a.M)
+	}
+}
@@ -1,11 +1,54 @@
 package autodocs
 
+import "time"
+
 // Page is a structure to store an individual page that has been
 // processed and ready to be displayed.
 type Page struct {
+	// Author is the name of the last person to change the page,
+	// when history is available.
+	Author string `json:"author,omitempty"`
+
+	// Modified is when the page was last changed, when history is
+	// available.
+	Modified *time.Time `json:"modified,omitempty"`
+
 	// Name is the displayable identifier for the page.
 	Name string `json:"name"`
 
 	// Content contains the parsed content for this page.
 	Content string `json:"content"`
+
+	// Permalink gives a link to this page pinned to the revision
+	// it was loaded from, when loaded from a past revision.
+	Permalink string `json:"permalink,omitempty"`
+
+	// Title is the title of the page, from the front matter, or
+	// else its first heading or name.
+	Title string `json:"title,omitempty"`
+
+	// Description summarises the page, from the front matter.
+	Description string `json:"description,omitempty"`
+
+	// Weight orders the page amongst others, from the front
+	// matter.
+	Weight int `json:"weight,omitempty"`
+
+	// Tags lists labels for the page, from the front matter.
+	Tags []string `json:"tags,omitempty"`
+
+	// Hidden marks the page as not to be listed, from the front
+	// matter.
+	Hidden bool `json:"hidden,omitempty"`
+
+	// Aliases lists other paths for the page, from the front
+	// matter.
+	Aliases []string `json:"aliases,omitempty"`
+
+	// Owner is who is responsible for the page, from the front
+	// matter.
+	Owner string `json:"owner,omitempty"`
+
+	// Meta holds any other keys within the front matter.
+	Meta map[string]interface{} `json:"meta,omitempty"`
 }
@@ -0,0 +1,17 @@
+package autodocs
+
+// Version is a structure to describe a single named revision of
+// the docs, such as a release branch or tag.
+type Version struct {
+	// Default marks the version served when none is requested.
+	Default bool `json:"default"`
+
+	// Kind is the type of reference, either "branch" or "tag".
+	Kind string `json:"kind"`
+
+	// Name is the short name of the reference.
+	Name string `json:"name"`
+
+	// Sha contains the commit the version points at.
+	Sha string `json:"-"`
+}